@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/snowflakedb/gosnowflake"
+)
+
+// accountPat matches account identifiers: orgname-accountname, or a legacy
+// account locator optionally followed by its region and cloud, e.g.
+// xy12345.us-east-2.aws.
+var accountPat = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*(\.[A-Za-z0-9][A-Za-z0-9-]*)*$`)
+
+// normalizeAccount cleans up an account identifier as commonly copied from an
+// account URL, e.g. https://myorg-myaccount.snowflakecomputing.com/, and
+// checks that it looks like one.
+func normalizeAccount(account string) (string, error) {
+	a := strings.TrimSpace(account)
+	a = strings.TrimPrefix(strings.TrimPrefix(a, "https://"), "http://")
+	a = strings.TrimSuffix(a, "/")
+	for _, domain := range []string{".snowflakecomputing.com", ".snowflakecomputing.cn"} {
+		if strings.HasSuffix(strings.ToLower(a), domain) {
+			a = a[:len(a)-len(domain)]
+		}
+	}
+	if !accountPat.MatchString(a) {
+		return "", fmt.Errorf("Invalid account %q, expected an account identifier such as myorg-myaccount, or an account locator such as xy12345 or xy12345.us-east-2.aws. Both are shown in Snowsight under the account menu", account)
+	}
+	return a, nil
+}
+
+// configureAccount sets the account of cfg. The region of legacy account
+// locators is split off for the driver to build the host from, and accounts
+// with underscores in their name get a host with hyphens instead, since
+// underscores aren't valid in host names. Hosts set explicitly are left
+// alone.
+func configureAccount(cfg *gosnowflake.Config, account string) {
+	name, region, hasRegion := strings.Cut(account, ".")
+	cfg.Account = name
+	if cfg.Host != "" {
+		return
+	}
+	if hasRegion {
+		cfg.Region = region
+		return
+	}
+	if strings.Contains(name, "_") {
+		cfg.Host = strings.ToLower(strings.ReplaceAll(name, "_", "-")) + ".snowflakecomputing.com"
+	}
+}
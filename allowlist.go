@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// stringListFlag is a flag that can be given multiple times.
+type stringListFlag []string
+
+func (f *stringListFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *stringListFlag) Set(v string) error {
+	*f = append(*f, v)
+	return nil
+}
+
+// databaseAllowlist is the set of databases that may be accessed, keyed by
+// their exact name. A nil databaseAllowlist allows all databases.
+type databaseAllowlist map[string]bool
+
+// newDatabaseAllowlist returns an allowlist of the given database
+// identifiers, or nil if there are none.
+func newDatabaseAllowlist(names []string) (databaseAllowlist, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	a := databaseAllowlist{}
+	for _, n := range names {
+		name, err := parseIdent(n)
+		if err != nil {
+			return nil, err
+		}
+		a[name] = true
+	}
+	return a, nil
+}
+
+// names returns the allowed database names in order.
+func (a databaseAllowlist) names() []string {
+	names := make([]string, 0, len(a))
+	for n := range a {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// check returns an error if the database with the exact name is not allowed.
+func (a databaseAllowlist) check(name string) error {
+	if a == nil || a[name] {
+		return nil
+	}
+	return newArgError("Access to database %s is not allowed", name)
+}
+
+// checkIdent is like check but takes a possibly quoted identifier.
+func (a databaseAllowlist) checkIdent(ident string) error {
+	if a == nil {
+		return nil
+	}
+	name, err := parseIdent(ident)
+	if err != nil {
+		return err
+	}
+	return a.check(name)
+}
+
+// checkTable checks the database of a fully qualified table name.
+func (a databaseAllowlist) checkTable(table string) error {
+	if a == nil {
+		return nil
+	}
+	dbName, _, _, err := parseTableName(table)
+	if err != nil {
+		return err
+	}
+	return a.check(dbName)
+}
+
+// checkURIName checks a database name taken from a resource URI. Such names
+// are resolved the same way as by sqlIdent.
+func (a databaseAllowlist) checkURIName(name string) error {
+	if !quoteIdentifiers && unquotedIdentPat.MatchString(name) {
+		name = strings.ToUpper(name)
+	}
+	return a.check(name)
+}
+
+var (
+	// sqlSkipPat matches string literals and comments of a query.
+	sqlSkipPat = regexp.MustCompile(`(?s)'(?:[^'\\]|''|\\.)*'|\$\$.*?\$\$|--[^\n]*|//[^\n]*|/\*.*?\*/`)
+	// sqlObjectPat matches database.schema.object and database..object
+	// names, capturing the database.
+	sqlObjectPat = regexp.MustCompile(`(?:^|[^A-Za-z0-9_$".])("(?:[^"]|"")+"|[A-Za-z_][A-Za-z0-9_$]*)\s*\.\s*(?:"(?:[^"]|"")+"|[A-Za-z_][A-Za-z0-9_$]*)?\s*\.\s*(?:"(?:[^"]|"")+"|[A-Za-z_][A-Za-z0-9_$]*)`)
+	// sqlDatabasePat matches explicit references to a database such as
+	// USE DATABASE and IN DATABASE, capturing the database.
+	sqlDatabasePat = regexp.MustCompile(`(?i)\bDATABASE\s+(?:IF\s+(?:NOT\s+)?EXISTS\s+)?("(?:[^"]|"")+"|[A-Za-z_][A-Za-z0-9_$]*)`)
+)
+
+// checkQuery makes a best effort check that query only references allowed
+// databases. Only fully qualified object names and explicit database
+// references are recognised, so unqualified names resolved against the
+// current database are not checked.
+func (a databaseAllowlist) checkQuery(query string) error {
+	if a == nil {
+		return nil
+	}
+	query = sqlSkipPat.ReplaceAllString(query, " ")
+	for _, pat := range []*regexp.Regexp{sqlObjectPat, sqlDatabasePat} {
+		for _, m := range pat.FindAllStringSubmatch(query, -1) {
+			// Names in SQL resolve as usual regardless of
+			// quoteIdentifiers.
+			parts, err := splitName(m[1], true)
+			if err != nil {
+				return err
+			}
+			if err := a.check(parts[0]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// wrap returns a resource handler that rejects URIs of databases that are
+// not allowed before calling h.
+func (a databaseAllowlist) wrap(h func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error)) func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	if a == nil {
+		return h
+	}
+	return func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		rest, ok := strings.CutPrefix(request.Params.URI, "snowflake://")
+		if !ok {
+			return nil, fmt.Errorf("Invalid URI")
+		}
+		rest, _, _ = strings.Cut(rest, "?")
+		seg, _, _ := strings.Cut(rest, "/")
+		if seg != "" {
+			name, err := url.PathUnescape(seg)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid URI segment %q: %v", seg, err)
+			}
+			if err := a.checkURIName(name); err != nil {
+				return nil, err
+			}
+		}
+		return h(ctx, request)
+	}
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/decimal128"
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+// arrowType returns the Arrow type used for a column. Types without a close
+// Arrow equivalent, such as VARIANT, are encoded as strings.
+func arrowType(ct *sql.ColumnType) arrow.DataType {
+	switch ct.DatabaseTypeName() {
+	case "FIXED":
+		precision, scale, ok := ct.DecimalSize()
+		if !ok || precision > 38 {
+			return arrow.BinaryTypes.String
+		}
+		if scale == 0 && precision <= 18 {
+			return arrow.PrimitiveTypes.Int64
+		}
+		return &arrow.Decimal128Type{Precision: int32(precision), Scale: int32(scale)}
+	case "REAL":
+		return arrow.PrimitiveTypes.Float64
+	case "BOOLEAN":
+		return arrow.FixedWidthTypes.Boolean
+	case "DATE":
+		return arrow.FixedWidthTypes.Date32
+	case "TIME":
+		return arrow.FixedWidthTypes.Time64ns
+	case "TIMESTAMP_NTZ":
+		return &arrow.TimestampType{Unit: arrow.Nanosecond}
+	case "TIMESTAMP_LTZ", "TIMESTAMP_TZ":
+		return &arrow.TimestampType{Unit: arrow.Nanosecond, TimeZone: "UTC"}
+	case "BINARY":
+		return arrow.BinaryTypes.Binary
+	}
+	return arrow.BinaryTypes.String
+}
+
+// appendArrowValue appends a value as returned by the driver to b.
+func appendArrowValue(b array.Builder, v any) error {
+	if v == nil {
+		b.AppendNull()
+		return nil
+	}
+	switch b := b.(type) {
+	case *array.Int64Builder:
+		n, err := strconv.ParseInt(fmt.Sprint(v), 10, 64)
+		if err != nil {
+			return err
+		}
+		b.Append(n)
+	case *array.Decimal128Builder:
+		t := b.Type().(*arrow.Decimal128Type)
+		n, err := decimal128.FromString(fmt.Sprint(v), t.Precision, t.Scale)
+		if err != nil {
+			return err
+		}
+		b.Append(n)
+	case *array.Float64Builder:
+		f, ok := v.(float64)
+		if !ok {
+			var err error
+			if f, err = strconv.ParseFloat(fmt.Sprint(v), 64); err != nil {
+				return err
+			}
+		}
+		b.Append(f)
+	case *array.BooleanBuilder:
+		x, ok := v.(bool)
+		if !ok {
+			return fmt.Errorf("Unexpected boolean value %v", v)
+		}
+		b.Append(x)
+	case *array.Date32Builder:
+		t, ok := v.(time.Time)
+		if !ok {
+			return fmt.Errorf("Unexpected date value %v", v)
+		}
+		b.Append(arrow.Date32FromTime(t))
+	case *array.Time64Builder:
+		t, ok := v.(time.Time)
+		if !ok {
+			return fmt.Errorf("Unexpected time value %v", v)
+		}
+		midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+		b.Append(arrow.Time64(t.Sub(midnight).Nanoseconds()))
+	case *array.TimestampBuilder:
+		t, ok := v.(time.Time)
+		if !ok {
+			return fmt.Errorf("Unexpected timestamp value %v", v)
+		}
+		b.Append(arrow.Timestamp(t.UnixNano()))
+	case *array.BinaryBuilder:
+		x, ok := v.([]byte)
+		if !ok {
+			return fmt.Errorf("Unexpected binary value %v", v)
+		}
+		b.Append(x)
+	case *array.StringBuilder:
+		b.Append(fmt.Sprint(v))
+	default:
+		return fmt.Errorf("Unsupported Arrow type %s", b.Type())
+	}
+	return nil
+}
+
+// runArrow executes query and returns up to maxResultRows rows encoded as a
+// base64 Arrow IPC stream, along with a description of the schema. Values
+// are not rounded or truncated.
+func (r *queryRunner) runArrow(ctx context.Context, query string, args ...any) (result map[string]any, err error) {
+	query = r.limit(query)
+	start := time.Now()
+	defer func() { r.log.record(start, query, result, err) }()
+	res, err := r.fetch(ctx, false, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.checkCutOff(res); err != nil {
+		return nil, err
+	}
+	if len(res.next) > 0 {
+		return nil, newArgError("The arrow format doesn't support statements returning several result sets")
+	}
+
+	fields := []arrow.Field{}
+	columnInfo := []map[string]any{}
+	for _, ct := range res.columnTypes {
+		t := arrowType(ct)
+		fields = append(fields, arrow.Field{Name: ct.Name(), Type: t, Nullable: true})
+		columnInfo = append(columnInfo, map[string]any{
+			"name":       ct.Name(),
+			"type":       ct.DatabaseTypeName(),
+			"arrow_type": t.String(),
+		})
+	}
+	schema := arrow.NewSchema(fields, nil)
+
+	rb := array.NewRecordBuilder(memory.DefaultAllocator, schema)
+	defer rb.Release()
+	for _, row := range res.rows {
+		for i, v := range row {
+			if err := appendArrowValue(rb.Field(i), v); err != nil {
+				return nil, fmt.Errorf("Failed to encode column %s: %v", fields[i].Name, err)
+			}
+		}
+	}
+	rec := rb.NewRecord()
+	defer rec.Release()
+
+	b := bytes.NewBuffer(nil)
+	w := ipc.NewWriter(b, ipc.WithSchema(schema))
+	if err := w.Write(rec); err != nil {
+		return nil, fmt.Errorf("Failed to encode result: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("Failed to encode result: %v", err)
+	}
+	if r.opts.maxArrowBytes > 0 && b.Len() > r.opts.maxArrowBytes {
+		return nil, fmt.Errorf("Encoded result is %d bytes which is over the limit of %d bytes, select fewer rows or columns", b.Len(), r.opts.maxArrowBytes)
+	}
+
+	result = map[string]any{
+		"column_info": columnInfo,
+		"encoding":    "arrow_ipc_stream_base64",
+		"data":        base64.StdEncoding.EncodeToString(b.Bytes()),
+		"row_count":   len(res.rows),
+		"elapsed_ms":  time.Since(start).Milliseconds(),
+	}
+	if res.more {
+		result["notice"] = fmt.Sprintf("Only first %d rows are included", maxResultRows)
+	}
+	if res.queryID != "" {
+		result["query_id"] = res.queryID
+	}
+	return result, nil
+}
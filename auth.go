@@ -0,0 +1,167 @@
+package main
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/snowflakedb/gosnowflake"
+)
+
+// Authentication methods supported by the -auth flag.
+const (
+	authExternalBrowser = "externalbrowser"
+	authPAT             = "pat"
+	authPassword        = "password"
+)
+
+// authMethods maps the -auth methods to the authenticators they stand for.
+var authMethods = map[string]string{
+	authExternalBrowser: "externalbrowser",
+	authPAT:             "programmatic_access_token",
+	authPassword:        "snowflake",
+}
+
+// authenticatorTypes maps the authenticators supported by the
+// -authenticator flag to the driver's authentication types.
+var authenticatorTypes = map[string]gosnowflake.AuthType{
+	"snowflake":                 gosnowflake.AuthTypeSnowflake,
+	"username_password_mfa":     gosnowflake.AuthTypeUsernamePasswordMFA,
+	"externalbrowser":           gosnowflake.AuthTypeExternalBrowser,
+	"oauth":                     gosnowflake.AuthTypeOAuth,
+	"snowflake_jwt":             gosnowflake.AuthTypeJwt,
+	"programmatic_access_token": gosnowflake.AuthTypePat,
+}
+
+// authOptions holds the authentication related flags.
+type authOptions struct {
+	method string
+	// authenticator takes precedence over method if set.
+	authenticator  string
+	user           string
+	pat            string
+	privateKeyFile string
+
+	password           string
+	passcode           string
+	passcodeInPassword bool
+}
+
+// configureAuth sets up authentication in cfg according to opts.
+func configureAuth(cfg *gosnowflake.Config, opts authOptions) error {
+	cfg.User = opts.user
+	name := opts.authenticator
+	if name == "" {
+		var ok bool
+		if name, ok = authMethods[opts.method]; !ok {
+			return fmt.Errorf("Unknown authentication method %q", opts.method)
+		}
+	}
+	authType, ok := authenticatorTypes[strings.ToLower(name)]
+	if !ok {
+		names := make([]string, 0, len(authenticatorTypes))
+		for n := range authenticatorTypes {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("Unknown authenticator %q, must be one of %s", name, strings.Join(names, ", "))
+	}
+	passwordAuth := authType == gosnowflake.AuthTypeSnowflake || authType == gosnowflake.AuthTypeUsernamePasswordMFA
+	if !passwordAuth && (opts.passcode != "" || opts.passcodeInPassword) {
+		return fmt.Errorf("MFA passcode is only supported with password authentication")
+	}
+	cfg.Authenticator = authType
+	switch authType {
+	case gosnowflake.AuthTypeExternalBrowser:
+	case gosnowflake.AuthTypePat:
+		if opts.user == "" {
+			return fmt.Errorf("Please provide user for PAT authentication")
+		}
+		token := opts.pat
+		if token == "" {
+			token = os.Getenv("SNOWFLAKE_PAT")
+		}
+		if token == "" {
+			return fmt.Errorf("Please provide a programmatic access token with -pat, -pat-file or SNOWFLAKE_PAT. You can create one in Snowsight under your user profile or with ALTER USER ... ADD PROGRAMMATIC ACCESS TOKEN")
+		}
+		// The driver still gates PAT support behind this environment
+		// variable.
+		if err := os.Setenv("ENABLE_EXPERIMENTAL_AUTHENTICATION", "true"); err != nil {
+			return fmt.Errorf("Failed to enable PAT authentication: %w", err)
+		}
+		cfg.Token = token
+	case gosnowflake.AuthTypeSnowflake, gosnowflake.AuthTypeUsernamePasswordMFA:
+		if opts.user == "" {
+			return fmt.Errorf("Please provide user for password authentication")
+		}
+		if opts.password == "" {
+			return fmt.Errorf("Please provide the password in SNOWFLAKE_PASSWORD or -password-file")
+		}
+		if opts.passcode != "" && opts.passcodeInPassword {
+			return fmt.Errorf("Please provide either passcode or passcode-in-password, not both")
+		}
+		cfg.Password = opts.password
+		cfg.Passcode = opts.passcode
+		cfg.PasscodeInPassword = opts.passcodeInPassword
+	case gosnowflake.AuthTypeOAuth:
+		cfg.Token = opts.pat
+		if cfg.Token == "" {
+			cfg.Token = os.Getenv("SNOWFLAKE_TOKEN")
+		}
+		if cfg.Token == "" {
+			return fmt.Errorf("Please provide the OAuth access token with -pat, -pat-file or SNOWFLAKE_TOKEN")
+		}
+	case gosnowflake.AuthTypeJwt:
+		if opts.user == "" {
+			return fmt.Errorf("Please provide user for key pair authentication")
+		}
+		key, err := readPrivateKey(opts.privateKeyFile)
+		if err != nil {
+			return err
+		}
+		cfg.PrivateKey = key
+	}
+	return nil
+}
+
+// readPrivateKey reads an unencrypted RSA private key in PKCS #8 PEM format
+// for key pair authentication.
+func readPrivateKey(path string) (*rsa.PrivateKey, error) {
+	if path == "" {
+		return nil, fmt.Errorf("Please provide the private key file with -private-key-file")
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read private key: %w", err)
+	}
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, fmt.Errorf("Failed to read private key: %s is not in PEM format", path)
+	}
+	if block.Type == "ENCRYPTED PRIVATE KEY" {
+		return nil, fmt.Errorf("Encrypted private keys are not supported, decrypt it with openssl pkcs8 -topk8 -nocrypt")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse private key: %w", err)
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("Private key must be an RSA key")
+	}
+	return rsaKey, nil
+}
+
+// explainAuthError replaces authentication and session errors, such as an
+// expired token or password, with a message telling the operator what to do.
+// Other errors are returned as is.
+func explainAuthError(err error) error {
+	if err == nil || !isAuthError(err) {
+		return err
+	}
+	return fmt.Errorf("Snowflake authentication failed or the session expired. The operator needs to re-authenticate, e.g. by renewing the token or password, or by restarting snowflake-mcp to log in again with external browser auth: %w", err)
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// limitKeywords are top-level keywords that already restrict the number of
+// rows a query returns.
+var limitKeywords = map[string]bool{
+	"LIMIT":  true,
+	"FETCH":  true,
+	"OFFSET": true,
+	"TOP":    true,
+}
+
+// addLimit appends a LIMIT clause to query if it is a single SELECT statement
+// without a top-level LIMIT, FETCH, OFFSET or TOP. It returns query unchanged
+// and false otherwise, e.g. for CTEs and multiple statements.
+func addLimit(query string, limit int) (string, bool) {
+	if leadingKeyword(query) != "SELECT" {
+		return query, false
+	}
+
+	// Scan the query skipping literals, quoted identifiers and comments,
+	// looking at keywords outside of parentheses.
+	depth := 0
+	end := -1
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == '\'' || c == '"':
+			j := i + 1
+			for j < len(query) {
+				if query[j] == '\\' && c == '\'' {
+					j += 2
+					continue
+				}
+				if query[j] == c {
+					if j+1 < len(query) && query[j+1] == c {
+						j += 2
+						continue
+					}
+					break
+				}
+				j++
+			}
+			if j >= len(query) {
+				return query, false
+			}
+			i = j + 1
+			continue
+		case strings.HasPrefix(query[i:], "$$"):
+			j := strings.Index(query[i+2:], "$$")
+			if j < 0 {
+				return query, false
+			}
+			i += j + 4
+			continue
+		case strings.HasPrefix(query[i:], "--"), strings.HasPrefix(query[i:], "//"):
+			j := strings.IndexByte(query[i:], '\n')
+			if j < 0 {
+				i = len(query)
+			} else {
+				i += j + 1
+			}
+			continue
+		case strings.HasPrefix(query[i:], "/*"):
+			j := strings.Index(query[i+2:], "*/")
+			if j < 0 {
+				return query, false
+			}
+			i += j + 4
+			continue
+		}
+		if end >= 0 {
+			// Anything but whitespace after the terminating semicolon is
+			// another statement.
+			if !unicode.IsSpace(rune(c)) {
+				return query, false
+			}
+			i++
+			continue
+		}
+		switch {
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == ';' && depth == 0:
+			end = i
+		case isIdentByte(c):
+			j := i
+			for j < len(query) && isIdentByte(query[j]) {
+				j++
+			}
+			if depth == 0 && limitKeywords[strings.ToUpper(query[i:j])] {
+				return query, false
+			}
+			i = j
+			continue
+		}
+		i++
+	}
+	if depth != 0 {
+		return query, false
+	}
+	if end >= 0 {
+		query = query[:end]
+	}
+	// The newline keeps the clause out of a trailing line comment.
+	return fmt.Sprintf("%s\nLIMIT %d", strings.TrimRightFunc(query, unicode.IsSpace), limit), true
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || c == '$' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
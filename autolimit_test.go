@@ -0,0 +1,38 @@
+package main
+
+import "testing"
+
+func TestAddLimit(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+		added bool
+	}{
+		{"SELECT * FROM t", "SELECT * FROM t\nLIMIT 10", true},
+		{"SELECT * FROM t;", "SELECT * FROM t\nLIMIT 10", true},
+		{"SELECT * FROM t;  \n", "SELECT * FROM t\nLIMIT 10", true},
+		{"SELECT * FROM t -- comment", "SELECT * FROM t -- comment\nLIMIT 10", true},
+		{"select a from t where b in (select b from u limit 5)", "select a from t where b in (select b from u limit 5)\nLIMIT 10", true},
+		{"SELECT 'limit' FROM t", "SELECT 'limit' FROM t\nLIMIT 10", true},
+		{`SELECT "LIMIT" FROM t`, `SELECT "LIMIT" FROM t` + "\nLIMIT 10", true},
+		{"SELECT $$ limit $$ FROM t", "SELECT $$ limit $$ FROM t\nLIMIT 10", true},
+		{"SELECT a /* limit */ FROM t", "SELECT a /* limit */ FROM t\nLIMIT 10", true},
+		{"SELECT * FROM t LIMIT 5", "SELECT * FROM t LIMIT 5", false},
+		{"SELECT * FROM t limit 5", "SELECT * FROM t limit 5", false},
+		{"SELECT * FROM t FETCH FIRST 5 ROWS ONLY", "SELECT * FROM t FETCH FIRST 5 ROWS ONLY", false},
+		{"SELECT * FROM t OFFSET 5", "SELECT * FROM t OFFSET 5", false},
+		{"SELECT TOP 5 * FROM t", "SELECT TOP 5 * FROM t", false},
+		{"WITH x AS (SELECT 1) SELECT * FROM x", "WITH x AS (SELECT 1) SELECT * FROM x", false},
+		{"SHOW TABLES", "SHOW TABLES", false},
+		{"SELECT 1; SELECT 2", "SELECT 1; SELECT 2", false},
+		{"SELECT 'unterminated", "SELECT 'unterminated", false},
+		{"SELECT (1", "SELECT (1", false},
+		{"SELECT 1 /* unterminated", "SELECT 1 /* unterminated", false},
+	}
+	for _, tt := range tests {
+		got, added := addLimit(tt.query, 10)
+		if got != tt.want || added != tt.added {
+			t.Errorf("addLimit(%q) = %q, %t, want %q, %t", tt.query, got, added, tt.want, tt.added)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// resourceCache caches resource contents by URI for a fixed TTL. A nil
+// *resourceCache disables caching.
+type resourceCache struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	entries map[string]resourceCacheEntry
+}
+
+type resourceCacheEntry struct {
+	contents []mcp.ResourceContents
+	expires  time.Time
+}
+
+func newResourceCache(ttl time.Duration) *resourceCache {
+	return &resourceCache{
+		ttl:     ttl,
+		entries: map[string]resourceCacheEntry{},
+	}
+}
+
+// wrap returns a resource handler that serves results of h from the cache
+// until they expire. Authentication errors of h are explained.
+func (c *resourceCache) wrap(h func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error)) func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	return func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		if c == nil {
+			contents, err := h(ctx, request)
+			return contents, explainAuthError(err)
+		}
+		uri := request.Params.URI
+		if contents, ok := c.get(uri); ok {
+			return contents, nil
+		}
+		contents, err := h(ctx, request)
+		if err != nil {
+			return nil, explainAuthError(err)
+		}
+		c.set(uri, contents)
+		return contents, nil
+	}
+}
+
+func (c *resourceCache) get(uri string) ([]mcp.ResourceContents, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[uri]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(e.expires) {
+		delete(c.entries, uri)
+		return nil, false
+	}
+	return e.contents, true
+}
+
+func (c *resourceCache) set(uri string, contents []mcp.ResourceContents) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[uri] = resourceCacheEntry{
+		contents: contents,
+		expires:  time.Now().Add(c.ttl),
+	}
+}
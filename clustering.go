@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// clusteringInfo returns how well a table is clustered as reported by
+// SYSTEM$CLUSTERING_INFORMATION, by its clustering key or by the given
+// columns. Tables without a clustering key are reported as such unless
+// columns are given.
+func clusteringInfo(ctx context.Context, db *sqlx.DB, dbName, schemaName, tableName string, columns []string) (map[string]any, error) {
+	table := quoteTableName(dbName, schemaName, tableName)
+	var key sql.NullString
+	if err := db.GetContext(ctx, &key, fmt.Sprintf(
+		"SELECT CLUSTERING_KEY FROM %s.INFORMATION_SCHEMA.TABLES WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?", quoteIdent(dbName),
+	), schemaName, tableName); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("Table %s not found", table)
+		}
+		return nil, fmt.Errorf("Failed to get clustering key of %s: %w", table, err)
+	}
+	result := map[string]any{
+		"table": table,
+	}
+	if key.Valid {
+		result["clustering_key"] = key.String
+	}
+
+	args := []any{table}
+	query := "SELECT SYSTEM$CLUSTERING_INFORMATION(?)"
+	if len(columns) > 0 {
+		quoted := make([]string, len(columns))
+		for i, c := range columns {
+			quoted[i] = quoteIdent(c)
+		}
+		args = append(args, "("+strings.Join(quoted, ", ")+")")
+		query = "SELECT SYSTEM$CLUSTERING_INFORMATION(?, ?)"
+	} else if !key.Valid {
+		result["message"] = "Table has no clustering key. Give columns to see how well it is clustered by them"
+		return result, nil
+	}
+	var raw string
+	if err := db.GetContext(ctx, &raw, query, args...); err != nil {
+		return nil, fmt.Errorf("Failed to get clustering information of %s: %w", table, err)
+	}
+	var info any
+	if err := json.Unmarshal([]byte(raw), &info); err != nil {
+		return nil, fmt.Errorf("Failed to parse clustering information: %v", err)
+	}
+	result["clustering_information"] = info
+	return result, nil
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// columnChange is a column present in both compared tables whose definition
+// differs.
+type columnChange struct {
+	Name      string `json:"name"`
+	LeftType  string `json:"left_type"`
+	RightType string `json:"right_type"`
+	// Nullability is only reported when it differs.
+	LeftNullable  *bool `json:"left_nullable,omitempty"`
+	RightNullable *bool `json:"right_nullable,omitempty"`
+}
+
+// tableDiff is the difference between the columns of two tables, from the
+// left table to the right one.
+type tableDiff struct {
+	Left    string         `json:"left"`
+	Right   string         `json:"right"`
+	Added   []tableColumn  `json:"added"`
+	Removed []tableColumn  `json:"removed"`
+	Changed []columnChange `json:"changed"`
+	// Identical is whether the tables have the same columns in the same
+	// order.
+	Identical bool `json:"identical"`
+}
+
+// compareTables returns the columns added, removed and changed going from the
+// left table to the right one, given by their quoted, qualified names.
+// Columns are matched by name.
+func compareTables(ctx context.Context, db *sqlx.DB, left, right string) (*tableDiff, error) {
+	leftColumns, err := describeTable(ctx, db, left)
+	if err != nil {
+		return nil, err
+	}
+	rightColumns, err := describeTable(ctx, db, right)
+	if err != nil {
+		return nil, err
+	}
+	diff := &tableDiff{
+		Left:    left,
+		Right:   right,
+		Added:   []tableColumn{},
+		Removed: []tableColumn{},
+		Changed: []columnChange{},
+	}
+	rightByName := map[string]tableColumn{}
+	for _, c := range rightColumns {
+		rightByName[c.Name] = c
+	}
+	leftNames := map[string]bool{}
+	for _, l := range leftColumns {
+		leftNames[l.Name] = true
+		r, ok := rightByName[l.Name]
+		if !ok {
+			diff.Removed = append(diff.Removed, l)
+			continue
+		}
+		change := columnChange{Name: l.Name, LeftType: l.Type, RightType: r.Type}
+		if l.Null != r.Null {
+			ln, rn := l.Null == "Y", r.Null == "Y"
+			change.LeftNullable, change.RightNullable = &ln, &rn
+		}
+		if l.Type != r.Type || change.LeftNullable != nil {
+			diff.Changed = append(diff.Changed, change)
+		}
+	}
+	for _, r := range rightColumns {
+		if !leftNames[r.Name] {
+			diff.Added = append(diff.Added, r)
+		}
+	}
+	diff.Identical = len(diff.Added) == 0 && len(diff.Removed) == 0 && len(diff.Changed) == 0
+	for i := 0; diff.Identical && i < len(leftColumns); i++ {
+		diff.Identical = leftColumns[i].Name == rightColumns[i].Name
+	}
+	return diff, nil
+}
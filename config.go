@@ -0,0 +1,323 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/snowflakedb/gosnowflake"
+)
+
+// config holds the command line options. Secrets given in files or the
+// environment and options of a named connection are filled in by load.
+type config struct {
+	connectionName     string
+	snowflakeAccount   string
+	snowflakeRole      string
+	snowflakeWarehouse string
+	snowflakeHost      string
+	snowflakePort      int
+	snowflakeProtocol  string
+	proxyHost          string
+	proxyPort          int
+	proxyUser          string
+	proxyPassword      string
+	proxyPasswordFile  string
+	ocspFailOpen       bool
+	snowflakeUser      string
+	authMethod         string
+	authenticator      string
+	privateKeyFile     string
+	pat                string
+	patFile            string
+	passwordFile       string
+	passcode           string
+	passcodeInPassword bool
+	connectRetries     int
+	connectTimeout     time.Duration
+	loginTimeout       time.Duration
+	requestTimeout     time.Duration
+	statementTimeout   int
+	timezone           string
+	queryTag           string
+	keepAlive          bool
+	maxOpenConns       int
+	maxConcurrent      int
+	queueTimeout       time.Duration
+	progressInterval   time.Duration
+	maxIdleConns       int
+	connMaxLifetime    time.Duration
+	sessionIsolation   string
+	transferDir        string
+	readOnly           bool
+	cacheTTL           time.Duration
+	noCache            bool
+	queryLogPath       string
+	maxCellBytes       int
+	floatPrecision     int
+	maxResponseBytes   int
+	maxArrowBytes      int
+	truncateMode       string
+	costWarnings       bool
+	structuredOutput   bool
+	reportTotal        bool
+	autoLimit          bool
+	allowedDatabases   stringListFlag
+	disabledTools      stringListFlag
+	sessionParamList   stringListFlag
+	quoteIdents        bool
+	hideSystemDBs      bool
+	disableResources   bool
+	mcpServerName      string
+	mcpServerVersion   string
+	queryRetries       int
+	defaultFormat      string
+	resultCacheDir     string
+	resultCacheSize    int64
+	maxResultPages     int
+	nullString         string
+	selfTestOnStart    bool
+	autoResume         bool
+	queryRetryDelay    time.Duration
+	logLevel           string
+	logFormat          string
+
+	// password is taken from SNOWFLAKE_PASSWORD, -password-file or the named
+	// connection.
+	password string
+}
+
+// parseFlags parses the command line into a config.
+func parseFlags() *config {
+	c := &config{}
+	flag.StringVar(&c.connectionName, "connection", "", "Name of a connection in connections.toml (in SNOWFLAKE_HOME or ~/.snowflake) to take connection options from. Flags given explicitly take precedence")
+	flag.StringVar(&c.snowflakeAccount, "account", "", "Snowflake account identifier, e.g. myorg-myaccount, or legacy account locator, e.g. xy12345.us-east-2.aws")
+	flag.StringVar(&c.snowflakeRole, "role", "", "Snowflake role name. Defaults to the default role of the user")
+	flag.StringVar(&c.snowflakeWarehouse, "warehouse", "", "Snowflake warehouse name")
+	flag.StringVar(&c.snowflakeHost, "host", "", "Snowflake host name, e.g. for PrivateLink. Defaults to the host derived from the account")
+	flag.IntVar(&c.snowflakePort, "port", 0, "Snowflake port, defaults to 443")
+	flag.StringVar(&c.snowflakeProtocol, "protocol", "", "Protocol to connect to Snowflake with: https or http. Defaults to https")
+	flag.StringVar(&c.proxyHost, "proxy-host", "", "HTTP proxy host to connect to Snowflake through. HTTPS_PROXY and NO_PROXY are also honored")
+	flag.IntVar(&c.proxyPort, "proxy-port", 0, "HTTP proxy port")
+	flag.StringVar(&c.proxyUser, "proxy-user", "", "HTTP proxy user name")
+	flag.StringVar(&c.proxyPassword, "proxy-password", "", "HTTP proxy password. Prefer setting SNOWFLAKE_PROXY_PASSWORD or -proxy-password-file to keep it out of the process list")
+	flag.StringVar(&c.proxyPasswordFile, "proxy-password-file", "", "File to read the HTTP proxy password from")
+	flag.BoolVar(&c.ocspFailOpen, "ocsp-fail-open", false, "Allow connecting when the OCSP responder checking Snowflake certificates for revocation can't be reached, instead of failing")
+	flag.StringVar(&c.snowflakeUser, "user", "", "Snowflake user name, required by some authentication methods")
+	flag.StringVar(&c.authMethod, "auth", authExternalBrowser, "Authentication method: externalbrowser, pat or password. The password is read from SNOWFLAKE_PASSWORD")
+	flag.StringVar(&c.authenticator, "authenticator", "", "Driver authenticator to use instead of -auth: snowflake, username_password_mfa, externalbrowser, oauth, snowflake_jwt or programmatic_access_token. The OAuth token is read from SNOWFLAKE_TOKEN")
+	flag.StringVar(&c.privateKeyFile, "private-key-file", "", "Unencrypted PKCS #8 PEM file with the RSA private key for snowflake_jwt authentication")
+	flag.StringVar(&c.pat, "pat", "", "Programmatic access token for pat authentication, or access token for oauth authentication. Prefer setting SNOWFLAKE_PAT or -pat-file to keep it out of the process list")
+	flag.StringVar(&c.patFile, "pat-file", "", "File to read the programmatic access token or OAuth access token from")
+	flag.StringVar(&c.passwordFile, "password-file", "", "File to read the password for password authentication from, instead of SNOWFLAKE_PASSWORD")
+	flag.StringVar(&c.passcode, "passcode", "", "MFA passcode for password authentication")
+	flag.BoolVar(&c.passcodeInPassword, "passcode-in-password", false, "The MFA passcode is appended to the password for password authentication")
+	flag.IntVar(&c.connectRetries, "connect-retries", 3, "Number of times to retry connecting to Snowflake on startup")
+	flag.DurationVar(&c.connectTimeout, "connect-timeout", 2*time.Minute, "Timeout for each attempt to connect to Snowflake on startup")
+	flag.DurationVar(&c.loginTimeout, "login-timeout", time.Minute, "How long to keep retrying logging in to Snowflake before giving up, not counting the wait for the external browser")
+	flag.DurationVar(&c.requestTimeout, "request-timeout", 0, "How long to keep retrying other requests to Snowflake, such as queries, after network errors before giving up (0 keeps retrying for as long as the query runs)")
+	flag.IntVar(&c.statementTimeout, "statement-timeout", 0, "Snowflake STATEMENT_TIMEOUT_IN_SECONDS for every session, which cancels long running queries on the server (0 keeps the account default)")
+	flag.StringVar(&c.timezone, "timezone", "", "IANA time zone of every session, e.g. UTC or Europe/Berlin. Defaults to the account's TIMEZONE parameter")
+	flag.StringVar(&c.queryTag, "query-tag", "snowflake-mcp/"+version, "QUERY_TAG set on every session so that queries of the agent can be found in the query history. Empty to not set one")
+	flag.BoolVar(&c.keepAlive, "keep-alive", false, "Keep idle Snowflake sessions from expiring by having the driver send a heartbeat every hour")
+	flag.IntVar(&c.maxOpenConns, "max-open-conns", 2, "Maximum number of open connections (Snowflake sessions) to Snowflake, 0 for unlimited")
+	flag.IntVar(&c.maxConcurrent, "max-concurrent-queries", 0, "Maximum number of tool calls running queries at once, 0 for unlimited. Further calls wait up to -query-queue-timeout")
+	flag.DurationVar(&c.queueTimeout, "query-queue-timeout", 30*time.Second, "How long tool calls wait for one of -max-concurrent-queries to finish before failing as busy")
+	flag.DurationVar(&c.progressInterval, "progress-interval", 10*time.Second, "How often to send progress notifications while a tool call runs, to clients asking for them with a progress token. 0 to disable")
+	flag.IntVar(&c.maxIdleConns, "max-idle-conns", 2, "Maximum number of idle connections kept open")
+	flag.DurationVar(&c.connMaxLifetime, "conn-max-lifetime", 0, "Maximum time a connection is reused for, 0 for unlimited")
+	flag.StringVar(&c.sessionIsolation, "session-isolation", sessionIsolationShared, "Whether query and execute calls share the sessions of the pool (shared) or each run in a new session that is discarded afterwards (per-request), so that session state changed with e.g. USE doesn't carry over to later calls at the cost of logging in again for every call")
+	flag.StringVar(&c.transferDir, "transfer-dir", "", "Local directory the put_file tool may upload from and get_file may download to. The file transfer tools are disabled unless set")
+	flag.BoolVar(&c.readOnly, "read-only", false, "Disable the execute tool and reject queries that are not read-only")
+	flag.DurationVar(&c.cacheTTL, "cache-ttl", time.Minute, "How long resource listings and definitions are cached for")
+	flag.BoolVar(&c.noCache, "no-cache", false, "Disable caching of resources")
+	flag.StringVar(&c.queryLogPath, "query-log", "", "File to append executed queries to as JSON lines, or - for stderr")
+	flag.IntVar(&c.maxCellBytes, "max-cell-bytes", 4096, "Truncate string and binary values in query results longer than this many bytes, 0 to disable")
+	flag.IntVar(&c.floatPrecision, "float-precision", 0, "Round FLOAT values in query results to this many significant digits (0 keeps full precision). Rounding hides floating point noise at the cost of precision")
+	flag.IntVar(&c.maxResponseBytes, "max-response-bytes", 1<<20, "Stop fetching query results once the rows take up more than this many bytes of JSON, 0 to disable")
+	flag.IntVar(&c.maxArrowBytes, "max-arrow-bytes", 10<<20, "Largest size in bytes of query results in the arrow format, 0 for unlimited")
+	flag.StringVar(&c.truncateMode, "truncate-mode", truncateModeTruncate, "What the query tool does with results over the row cap: truncate returns the first rows with a notice, error fails the query so that it gets narrowed down")
+	flag.BoolVar(&c.costWarnings, "cost-warnings", false, "Explain SELECT queries run with the query tool and warn about large tables scanned in full. This adds an EXPLAIN to every such query")
+	flag.BoolVar(&c.structuredOutput, "structured-output", false, "Add query results as a JSON object of a fixed shape, described in the tool descriptions, as the last content of query and get_results results, for clients that consume typed results")
+	flag.BoolVar(&c.reportTotal, "report-total", false, "When query results are cut off, run the query again as a SELECT COUNT(*) to report the total number of rows. This doubles the cost of such queries")
+	flag.BoolVar(&c.autoLimit, "auto-limit", false, "Add a LIMIT to simple SELECT queries without one so that Snowflake doesn't compute rows that would be discarded")
+	flag.BoolVar(&c.quoteIdents, "quote-identifiers", false, "Treat names in resource URIs and tool arguments as quoted identifiers, so that their case is preserved instead of being uppercased")
+	flag.BoolVar(&c.hideSystemDBs, "hide-system-databases", true, "Leave the SNOWFLAKE and SNOWFLAKE_SAMPLE_DATA databases out of the database list resource")
+	flag.BoolVar(&c.disableResources, "disable-resources", false, "Don't expose any resources")
+	flag.StringVar(&c.mcpServerName, "server-name", "Snowflake", "Server name reported to MCP clients")
+	flag.StringVar(&c.mcpServerVersion, "server-version", version, "Server version reported to MCP clients")
+	flag.IntVar(&c.queryRetries, "query-retries", 2, "Number of times to retry read-only queries and resources after transient failures such as network errors")
+	flag.StringVar(&c.defaultFormat, "result-format-default", formatJSON, "Format of query results when the query tool isn't given one: json, markdown or arrow")
+	flag.StringVar(&c.resultCacheDir, "result-cache-dir", "", "Directory to cache pages of results fetched with get_results in, so that paging through them again doesn't query Snowflake. Cached results are removed on exit. Caching is disabled unless set")
+	flag.Int64Var(&c.resultCacheSize, "result-cache-size", 100<<20, "Largest total size in bytes of the results cached in -result-cache-dir, beyond which the least recently used are removed")
+	flag.IntVar(&c.maxResultPages, "max-result-pages", 100, "Maximum number of pages of a query's results fetched with get_results, as a guard against paging through huge results. 0 for no limit")
+	flag.StringVar(&c.nullString, "null-string", "", "Text NULLs are rendered as in the markdown format, e.g. NULL. The json format always uses null")
+	flag.BoolVar(&c.selfTestOnStart, "self-test", false, "Check on startup what the role can do, e.g. list databases and use the warehouse, and log the results")
+	flag.BoolVar(&c.autoResume, "auto-resume", false, "Resume the warehouse and retry when a tool call fails because the warehouse is suspended")
+	flag.DurationVar(&c.queryRetryDelay, "query-retry-delay", time.Second, "Delay before the first retry of a query, doubling on each retry")
+	flag.StringVar(&c.logLevel, "log-level", "info", "Log level: debug, info, warn or error")
+	flag.StringVar(&c.logFormat, "log-format", "text", "Log format: text or json. Logs are written to stderr")
+	flag.Var(&c.allowedDatabases, "allowed-database", "Database that may be accessed, can be given multiple times. If not given, all databases the role has access to may be accessed")
+	flag.Var(&c.sessionParamList, "session-param", "Session parameter to set for every session as KEY=VALUE, e.g. TIMEZONE=UTC, can be given multiple times")
+	flag.Var(&c.disabledTools, "disable-tool", "Tool to not expose, can be given multiple times")
+	flag.Parse()
+	return c
+}
+
+// load fills in the options of the named connection and the secrets given in
+// files or the environment, then validates c.
+func (c *config) load() error {
+	connectionPassword := ""
+	if c.connectionName != "" {
+		p, err := loadConnection(c.connectionName)
+		if err != nil {
+			return err
+		}
+		connectionPassword = p
+	}
+	if c.snowflakeAccount == "" {
+		return fmt.Errorf("Please provide account")
+	}
+	if c.snowflakeRole == "" {
+		slog.Info("No role given, using the default role of the user")
+	}
+	account, err := normalizeAccount(c.snowflakeAccount)
+	if err != nil {
+		return err
+	}
+	c.snowflakeAccount = account
+	if err := checkHost(c.snowflakeAccount, c.snowflakeHost); err != nil {
+		return err
+	}
+	if c.snowflakeProtocol != "" && c.snowflakeProtocol != "https" && c.snowflakeProtocol != "http" {
+		return fmt.Errorf("Protocol must be https or http")
+	}
+	if c.snowflakePort < 0 || c.snowflakePort > 65535 {
+		return fmt.Errorf("Port must be between 1 and 65535, or 0 for the default")
+	}
+	if err := loadSecretFile(&c.proxyPassword, c.proxyPasswordFile, "proxy-password"); err != nil {
+		return err
+	}
+	if c.proxyPassword == "" {
+		c.proxyPassword = os.Getenv("SNOWFLAKE_PROXY_PASSWORD")
+	}
+	if err := loadSecretFile(&c.pat, c.patFile, "pat"); err != nil {
+		return err
+	}
+	// The password is taken from the named connection unless given
+	// otherwise.
+	c.password = os.Getenv("SNOWFLAKE_PASSWORD")
+	if c.password == "" {
+		c.password = connectionPassword
+	}
+	if c.passwordFile != "" {
+		p, err := readSecretFile(c.passwordFile)
+		if err != nil {
+			return err
+		}
+		c.password = p
+	}
+	if err := configureProxy(c.proxyHost, c.proxyPort, c.proxyUser, c.proxyPassword); err != nil {
+		return err
+	}
+	if c.loginTimeout <= 0 {
+		return fmt.Errorf("Login timeout must be positive")
+	}
+	if c.requestTimeout < 0 {
+		return fmt.Errorf("Request timeout must not be negative")
+	}
+	if c.statementTimeout < 0 {
+		return fmt.Errorf("Statement timeout must be a positive number of seconds")
+	}
+	if c.sessionIsolation != sessionIsolationShared && c.sessionIsolation != sessionIsolationPerRequest {
+		return fmt.Errorf("Session isolation must be shared or per-request")
+	}
+	switch c.defaultFormat {
+	case formatJSON, formatMarkdown, formatArrow:
+	default:
+		return fmt.Errorf("Default result format must be json, markdown or arrow")
+	}
+	if c.truncateMode != truncateModeTruncate && c.truncateMode != truncateModeError {
+		return fmt.Errorf("Truncate mode must be truncate or error")
+	}
+	if c.maxResultPages < 0 {
+		return fmt.Errorf("Maximum result pages must not be negative")
+	}
+	if c.floatPrecision < 0 {
+		return fmt.Errorf("Float precision must not be negative")
+	}
+	return nil
+}
+
+// snowflakeConfig returns the driver configuration for c, along with the
+// session parameters given with -session-param so that they can be checked
+// once connected.
+func (c *config) snowflakeConfig() (gosnowflake.Config, map[string]string, error) {
+	sfconfig := gosnowflake.Config{
+		Role:      c.snowflakeRole,
+		Warehouse: c.snowflakeWarehouse,
+		Host:      c.snowflakeHost,
+		Port:      c.snowflakePort,
+		Protocol:  c.snowflakeProtocol,
+		Params:    map[string]*string{},
+		// Shows up as the client application in the login history.
+		Application: "snowflake-mcp",
+
+		OCSPFailOpen:   gosnowflake.OCSPFailOpenFalse,
+		LoginTimeout:   c.loginTimeout,
+		RequestTimeout: c.requestTimeout,
+	}
+	configureAccount(&sfconfig, c.snowflakeAccount)
+	if c.ocspFailOpen {
+		sfconfig.OCSPFailOpen = gosnowflake.OCSPFailOpenTrue
+	}
+	if err := configureAuth(&sfconfig, authOptions{
+		method:         c.authMethod,
+		authenticator:  c.authenticator,
+		user:           c.snowflakeUser,
+		pat:            c.pat,
+		privateKeyFile: c.privateKeyFile,
+
+		password:           c.password,
+		passcode:           c.passcode,
+		passcodeInPassword: c.passcodeInPassword,
+	}); err != nil {
+		return gosnowflake.Config{}, nil, err
+	}
+	// Session parameters are set on login so that they apply to every
+	// connection in the pool, not just the first.
+	sessionParams, err := parseSessionParams(c.sessionParamList)
+	if err != nil {
+		return gosnowflake.Config{}, nil, err
+	}
+	if c.timezone != "" {
+		if _, ok := sessionParams["TIMEZONE"]; ok {
+			return gosnowflake.Config{}, nil, fmt.Errorf("Please provide either -timezone or -session-param=TIMEZONE=..., not both")
+		}
+		if _, err := time.LoadLocation(c.timezone); err != nil || c.timezone == "Local" {
+			return gosnowflake.Config{}, nil, fmt.Errorf("Invalid time zone %q, must be an IANA time zone name such as UTC or Europe/Berlin", c.timezone)
+		}
+		sfconfig.Params["TIMEZONE"] = &c.timezone
+	}
+	if _, ok := sessionParams["QUERY_TAG"]; !ok && c.queryTag != "" {
+		sfconfig.Params["QUERY_TAG"] = &c.queryTag
+	}
+	for k, v := range sessionParams {
+		sfconfig.Params[k] = &v
+	}
+	if c.statementTimeout > 0 {
+		if _, ok := sessionParams["STATEMENT_TIMEOUT_IN_SECONDS"]; ok {
+			return gosnowflake.Config{}, nil, fmt.Errorf("Please provide either -statement-timeout or -session-param=STATEMENT_TIMEOUT_IN_SECONDS=..., not both")
+		}
+		v := strconv.Itoa(c.statementTimeout)
+		sfconfig.Params["STATEMENT_TIMEOUT_IN_SECONDS"] = &v
+	}
+	if c.keepAlive {
+		v := "true"
+		sfconfig.Params["client_session_keep_alive"] = &v
+	}
+	return sfconfig, sessionParams, nil
+}
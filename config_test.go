@@ -0,0 +1,34 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSnowflakeConfigSessionParams(t *testing.T) {
+	tests := []struct {
+		c    config
+		want string
+	}{
+		{config{statementTimeout: 60, sessionParamList: stringListFlag{"statement_timeout_in_seconds=30"}}, "either -statement-timeout or -session-param=STATEMENT_TIMEOUT_IN_SECONDS"},
+		{config{timezone: "UTC", sessionParamList: stringListFlag{"TIMEZONE=UTC"}}, "either -timezone or -session-param=TIMEZONE"},
+	}
+	for _, tt := range tests {
+		tt.c.authMethod = authExternalBrowser
+		if _, _, err := tt.c.snowflakeConfig(); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("snowflakeConfig() = %v, want an error containing %q", err, tt.want)
+		}
+	}
+
+	c := config{authMethod: authExternalBrowser, statementTimeout: 60, sessionParamList: stringListFlag{"QUERY_TAG=x"}}
+	sfconfig, params, err := c.snowflakeConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := sfconfig.Params["STATEMENT_TIMEOUT_IN_SECONDS"]; v == nil || *v != "60" {
+		t.Errorf("STATEMENT_TIMEOUT_IN_SECONDS is %v, want 60", v)
+	}
+	if params["QUERY_TAG"] != "x" {
+		t.Errorf("Session parameters are %v", params)
+	}
+}
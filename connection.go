@@ -0,0 +1,94 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// connectionFlags maps keys of a connections.toml entry to the flags they
+// set.
+var connectionFlags = map[string]string{
+	"account":            "account",
+	"user":               "user",
+	"username":           "user",
+	"role":               "role",
+	"warehouse":          "warehouse",
+	"host":               "host",
+	"port":               "port",
+	"protocol":           "protocol",
+	"authenticator":      "authenticator",
+	"private_key_file":   "private-key-file",
+	"private_key_path":   "private-key-file",
+	"token":              "pat",
+	"passcode":           "passcode",
+	"passcodeinpassword": "passcode-in-password",
+	"ocspfailopen":       "ocsp-fail-open",
+}
+
+// connectionsFile returns the path of the connections.toml file shared with
+// the Snowflake CLI and drivers.
+func connectionsFile() (string, error) {
+	dir := os.Getenv("SNOWFLAKE_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("Failed to find home directory: %w", err)
+		}
+		dir = filepath.Join(home, ".snowflake")
+	}
+	return filepath.Join(dir, "connections.toml"), nil
+}
+
+// loadConnection sets flags from the named connection in connections.toml,
+// except those given explicitly on the command line. The password, if any, is
+// returned since it is not taken from a flag.
+func loadConnection(name string) (string, error) {
+	path, err := connectionsFile()
+	if err != nil {
+		return "", err
+	}
+	if runtime.GOOS != "windows" {
+		info, err := os.Stat(path)
+		if err != nil {
+			return "", fmt.Errorf("Failed to read connections file: %w", err)
+		}
+		if info.Mode().Perm()&0o077 != 0 {
+			return "", fmt.Errorf("Connections file %s must only be accessible by its owner, e.g. chmod 0600", path)
+		}
+	}
+	conns := map[string]map[string]any{}
+	if _, err := toml.DecodeFile(path, &conns); err != nil {
+		return "", fmt.Errorf("Failed to read connections file: %w", err)
+	}
+	conn, ok := conns[name]
+	if !ok {
+		return "", fmt.Errorf("Connection %q not found in %s", name, path)
+	}
+
+	set := map[string]bool{}
+	flag.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	password := ""
+	for k, v := range conn {
+		k = strings.ToLower(k)
+		value := fmt.Sprint(v)
+		if k == "password" {
+			password = value
+			continue
+		}
+		name, ok := connectionFlags[k]
+		if !ok || set[name] {
+			continue
+		}
+		if err := flag.Set(name, value); err != nil {
+			return "", fmt.Errorf("Invalid %s in connection: %w", k, err)
+		}
+		set[name] = true
+	}
+	return password, nil
+}
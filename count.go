@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// countRows returns the exact number of rows of a table, optionally only
+// those matching the where predicate, which may use bind parameters.
+func countRows(ctx context.Context, db *sqlx.DB, dbName, schemaName, tableName, where string, args []any) (map[string]any, error) {
+	table := quoteTableName(dbName, schemaName, tableName)
+	query := "SELECT COUNT(*) FROM " + table
+	if where != "" {
+		if len(splitStatements(where)) != 1 {
+			return nil, newArgError("Predicate must be a single expression")
+		}
+		// The predicate is put on lines of its own so that a trailing line
+		// comment can't swallow the closing parenthesis.
+		query += " WHERE (\n" + where + "\n)"
+	}
+	var count int64
+	if err := db.GetContext(ctx, &count, query, args...); err != nil {
+		return nil, fmt.Errorf("Failed to count rows of %s: %w", table, err)
+	}
+	result := map[string]any{
+		"table":     table,
+		"row_count": count,
+	}
+	if where != "" {
+		result["where"] = where
+	}
+	return result, nil
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// resultRetention is how long Snowflake keeps the results of a query for
+// RESULT_SCAN.
+const resultRetention = 24 * time.Hour
+
+// resultCursors tracks the results paged through with get_results, one cursor
+// per query ID. Pages are fetched with RESULT_SCAN, so a cursor holds no
+// connection or rows, only the pages fetched so far, which are capped by
+// maxPages. Cursors are closed with close_results or once the results expire.
+// With the stdio transport the server serves a single session, so the
+// cursors are that session's. A nil *resultCursors tracks nothing.
+type resultCursors struct {
+	// maxPages is the maximum number of distinct pages fetched per query
+	// ID, 0 for no limit.
+	maxPages int
+
+	mu   sync.Mutex
+	open map[string]*resultCursor
+}
+
+type resultCursor struct {
+	// offsets are the offsets of the pages fetched.
+	offsets map[int]bool
+	opened  time.Time
+}
+
+func newResultCursors(maxPages int) *resultCursors {
+	return &resultCursors{
+		maxPages: maxPages,
+		open:     map[string]*resultCursor{},
+	}
+}
+
+// fetch records fetching the page of the results of queryID at offset,
+// opening a cursor for queryID if needed. It fails if that would exceed
+// maxPages. Fetching a page again doesn't count towards the limit.
+func (c *resultCursors) fetch(queryID string, offset int) error {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.expire()
+	key := strings.ToLower(queryID)
+	cur, ok := c.open[key]
+	if !ok {
+		cur = &resultCursor{offsets: map[int]bool{}, opened: time.Now()}
+		c.open[key] = cur
+	}
+	if !cur.offsets[offset] && c.maxPages > 0 && len(cur.offsets) >= c.maxPages {
+		return newArgError("Already fetched %d pages of the results of query %s, the maximum. Narrow the query down or aggregate it instead of paging further", c.maxPages, queryID)
+	}
+	cur.offsets[offset] = true
+	return nil
+}
+
+// close closes the cursor of queryID and reports whether it was open.
+// Closing a cursor that isn't open does nothing.
+func (c *resultCursors) close(queryID string) bool {
+	if c == nil {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	key := strings.ToLower(queryID)
+	_, ok := c.open[key]
+	delete(c.open, key)
+	return ok
+}
+
+// count returns the number of open cursors.
+func (c *resultCursors) count() int {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.expire()
+	return len(c.open)
+}
+
+// expire closes the cursors of results that Snowflake no longer keeps. c.mu
+// must be held.
+func (c *resultCursors) expire() {
+	for key, cur := range c.open {
+		if time.Since(cur.opened) > resultRetention {
+			delete(c.open, key)
+		}
+	}
+}
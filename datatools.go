@@ -0,0 +1,306 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// registerDataTools registers the tools sampling, counting and checking the
+// data of tables.
+func registerDataTools(tools *toolRegistry, db *sqlx.DB, runner *queryRunner, allowed databaseAllowlist) {
+	// Add a join preview tool.
+	tools.add(mcp.NewTool(
+		"preview_join",
+		mcp.WithDescription("Run a small sample join between two tables to check that the join produces sensible results."),
+		mcp.WithString("left_table",
+			mcp.Required(),
+			mcp.Description("Fully qualified left table as database.schema.table."),
+		),
+		mcp.WithString("right_table",
+			mcp.Required(),
+			mcp.Description("Fully qualified right table as database.schema.table."),
+		),
+		mcp.WithString("left_column",
+			mcp.Description("Join column of the left table. If neither column is given, they are detected from foreign keys."),
+		),
+		mcp.WithString("right_column",
+			mcp.Description("Join column of the right table. Defaults to left_column."),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description(fmt.Sprintf("Number of sample rows to return, at most %d.", maxJoinPreviewRows)),
+			mcp.DefaultNumber(10),
+		),
+	), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		leftTable, err := stringArg(request.Params.Arguments, "left_table", true)
+		if err != nil {
+			return nil, err
+		}
+		rightTable, err := stringArg(request.Params.Arguments, "right_table", true)
+		if err != nil {
+			return nil, err
+		}
+		leftColumn, err := stringArg(request.Params.Arguments, "left_column", false)
+		if err != nil {
+			return nil, err
+		}
+		rightColumn, err := stringArg(request.Params.Arguments, "right_column", false)
+		if err != nil {
+			return nil, err
+		}
+		limit, err := intArg(request.Params.Arguments, "limit", 10)
+		if err != nil {
+			return nil, err
+		}
+		if limit < 1 || limit > maxJoinPreviewRows {
+			return nil, newArgError("Limit must be between 1 and %d", maxJoinPreviewRows)
+		}
+		for _, t := range []string{leftTable, rightTable} {
+			if err := allowed.checkTable(t); err != nil {
+				return nil, err
+			}
+		}
+		result, err := previewJoin(ctx, runner, leftTable, rightTable, leftColumn, rightColumn, limit)
+		if err != nil {
+			return nil, err
+		}
+		return jsonToolResult(result)
+	})
+
+	// Add a data quality tool.
+	tools.add(mcp.NewTool(
+		"data_quality",
+		mcp.WithDescription("Check a table for data quality issues such as high NULL rates, duplicate keys and out of range values."),
+		mcp.WithString("table",
+			mcp.Required(),
+			mcp.Description("Fully qualified table as database.schema.table."),
+		),
+		withProperty("checks", map[string]any{
+			"type":        "array",
+			"items":       map[string]any{"type": "string", "enum": []string{qualityCheckNulls, qualityCheckDuplicates, qualityCheckRange}},
+			"description": "Checks to run. Defaults to nulls and duplicates, plus range when ranges are given.",
+		}),
+		mcp.WithNumber("null_threshold",
+			mcp.Description("Report columns whose fraction of NULL values is above this threshold, between 0 and 1."),
+			mcp.DefaultNumber(0),
+		),
+		withProperty("key_columns", map[string]any{
+			"type":        "array",
+			"items":       map[string]any{"type": "string"},
+			"description": "Columns that must be unique together. Defaults to the primary key of the table.",
+		}),
+		withProperty("ranges", map[string]any{
+			"type":                 "object",
+			"additionalProperties": map[string]any{"type": "object", "properties": map[string]any{"min": map[string]any{}, "max": map[string]any{}}},
+			"description":          `Allowed ranges of values keyed by column, e.g. {"age": {"min": 0, "max": 150}}.`,
+		}),
+	), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		req := qualityRequest{}
+		var err error
+		if req.table, err = stringArg(request.Params.Arguments, "table", true); err != nil {
+			return nil, err
+		}
+		if req.nullThreshold, err = numberArg(request.Params.Arguments, "null_threshold", 0); err != nil {
+			return nil, err
+		}
+		if err := allowed.checkTable(req.table); err != nil {
+			return nil, err
+		}
+
+		if req.checks, err = stringSliceArg(request.Params.Arguments, "checks"); err != nil {
+			return nil, err
+		}
+		if req.keyColumns, err = stringSliceArg(request.Params.Arguments, "key_columns"); err != nil {
+			return nil, err
+		}
+		for i, c := range req.keyColumns {
+			if req.keyColumns[i], err = parseIdent(c); err != nil {
+				return nil, err
+			}
+		}
+		if v := request.Params.Arguments["ranges"]; v != nil {
+			ranges, ok := v.(map[string]any)
+			if !ok {
+				return nil, newArgError("Argument ranges must be an object")
+			}
+			req.ranges = map[string]qualityRange{}
+			for c, r := range ranges {
+				column, err := parseIdent(c)
+				if err != nil {
+					return nil, err
+				}
+				bounds, _ := r.(map[string]any)
+				qr := qualityRange{}
+				if qr.Min, err = bindValue(bounds["min"]); err != nil {
+					return nil, newArgError("Invalid minimum of %s: %v", c, err)
+				}
+				if qr.Max, err = bindValue(bounds["max"]); err != nil {
+					return nil, newArgError("Invalid maximum of %s: %v", c, err)
+				}
+				req.ranges[column] = qr
+			}
+		}
+		if len(req.checks) == 0 {
+			req.checks = []string{qualityCheckNulls, qualityCheckDuplicates}
+			if len(req.ranges) > 0 {
+				req.checks = append(req.checks, qualityCheckRange)
+			}
+		}
+
+		result, err := checkDataQuality(ctx, db, req)
+		if err != nil {
+			return nil, err
+		}
+		return jsonToolResult(result)
+	})
+
+	// Add a row count tool.
+	tools.add(mcp.NewTool(
+		"count_rows",
+		mcp.WithDescription("Count the rows of a table exactly, optionally only those matching a predicate. Use it when row counts estimated from metadata may be stale."),
+		mcp.WithString("database",
+			mcp.Required(),
+			mcp.Description("Database of the table."),
+		),
+		mcp.WithString("schema",
+			mcp.Required(),
+			mcp.Description("Schema of the table."),
+		),
+		mcp.WithString("table",
+			mcp.Required(),
+			mcp.Description("Name of the table."),
+		),
+		mcp.WithString("where",
+			mcp.Description("SQL predicate rows must match to be counted, e.g. status = ? AND created > '2024-01-01'."),
+		),
+		withProperty("params", map[string]any{
+			"type":        []string{"array", "object"},
+			"description": "Bind parameters for the predicate. Use an array for positional ? or :1 placeholders, or an object for :name placeholders.",
+		}),
+	), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		var names [3]string
+		for i, arg := range []string{"database", "schema", "table"} {
+			v, err := stringArg(request.Params.Arguments, arg, true)
+			if err != nil {
+				return nil, err
+			}
+			if names[i], err = parseIdent(v); err != nil {
+				return nil, err
+			}
+		}
+		where, err := stringArg(request.Params.Arguments, "where", false)
+		if err != nil {
+			return nil, err
+		}
+		args, err := bindParams(request.Params.Arguments["params"])
+		if err != nil {
+			return nil, err
+		}
+		if err := allowed.check(names[0]); err != nil {
+			return nil, err
+		}
+		if err := allowed.checkQuery(where); err != nil {
+			return nil, err
+		}
+		result, err := countRows(ctx, db, names[0], names[1], names[2], where, args)
+		if err != nil {
+			return nil, err
+		}
+		return jsonToolResult(result)
+	})
+
+	// Add a column profile tool.
+	tools.add(mcp.NewTool(
+		"profile_column",
+		mcp.WithDescription("Summarize the distribution of a column: row, NULL and distinct counts, NULL percentage, and minimum and maximum values."),
+		mcp.WithString("database",
+			mcp.Required(),
+			mcp.Description("Database of the table."),
+		),
+		mcp.WithString("schema",
+			mcp.Required(),
+			mcp.Description("Schema of the table."),
+		),
+		mcp.WithString("table",
+			mcp.Required(),
+			mcp.Description("Name of the table."),
+		),
+		mcp.WithString("column",
+			mcp.Required(),
+			mcp.Description("Name of the column."),
+		),
+	), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		var names [4]string
+		for i, arg := range []string{"database", "schema", "table", "column"} {
+			v, err := stringArg(request.Params.Arguments, arg, true)
+			if err != nil {
+				return nil, err
+			}
+			if names[i], err = parseIdent(v); err != nil {
+				return nil, err
+			}
+		}
+		if err := allowed.check(names[0]); err != nil {
+			return nil, err
+		}
+		result, err := profileColumn(ctx, runner, names[0], names[1], names[2], names[3])
+		if err != nil {
+			return nil, err
+		}
+		return jsonToolResult(result)
+	})
+
+	// Add a clustering information tool.
+	tools.add(mcp.NewTool(
+		"clustering_info",
+		mcp.WithDescription("Report how well a table is clustered, e.g. average clustering depth and partition overlaps, to find tables that prune poorly."),
+		mcp.WithString("database",
+			mcp.Required(),
+			mcp.Description("Database of the table."),
+		),
+		mcp.WithString("schema",
+			mcp.Required(),
+			mcp.Description("Schema of the table."),
+		),
+		mcp.WithString("table",
+			mcp.Required(),
+			mcp.Description("Name of the table."),
+		),
+		withProperty("columns", map[string]any{
+			"type":        "array",
+			"items":       map[string]any{"type": "string"},
+			"description": "Columns to report clustering by. Defaults to the clustering key of the table.",
+		}),
+	), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		var names [3]string
+		for i, arg := range []string{"database", "schema", "table"} {
+			v, err := stringArg(request.Params.Arguments, arg, true)
+			if err != nil {
+				return nil, err
+			}
+			if names[i], err = parseIdent(v); err != nil {
+				return nil, err
+			}
+		}
+		columns, err := stringSliceArg(request.Params.Arguments, "columns")
+		if err != nil {
+			return nil, err
+		}
+		for i, c := range columns {
+			if columns[i], err = parseIdent(c); err != nil {
+				return nil, err
+			}
+		}
+		if err := allowed.check(names[0]); err != nil {
+			return nil, err
+		}
+		result, err := clusteringInfo(ctx, db, names[0], names[1], names[2], columns)
+		if err != nil {
+			return nil, err
+		}
+		return jsonToolResult(result)
+	})
+}
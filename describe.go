@@ -0,0 +1,220 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// tableColumn is a column of a table or view as reported by DESCRIBE TABLE.
+type tableColumn struct {
+	Name       string  `db:"name" json:"name"`
+	Type       string  `db:"type" json:"type"`
+	Kind       string  `db:"kind" json:"-"`
+	Null       string  `db:"null?" json:"-"`
+	Default    *string `db:"default" json:"-"`
+	Expression *string `db:"expression" json:"-"`
+	Comment    *string `db:"comment" json:"comment,omitempty"`
+}
+
+// describeTable returns the columns of the table or view with the given
+// qualified name.
+func describeTable(ctx context.Context, db *sqlx.DB, name string) ([]tableColumn, error) {
+	rows, err := db.QueryxContext(ctx, "DESCRIBE TABLE "+name)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get table def for %s: %w", name, err)
+	}
+	defer rows.Close()
+
+	columns := []tableColumn{}
+	for rows.Next() {
+		t := tableColumn{}
+		if err = rows.StructScan(&t); err != nil {
+			return nil, fmt.Errorf("Failed to scan rows: %v", err)
+		}
+		if t.Kind != "COLUMN" {
+			continue
+		}
+		columns = append(columns, t)
+	}
+	return columns, nil
+}
+
+// tableInfo is the metadata of a table or view as reported by SHOW TABLES
+// and the like. Rows is only reported for tables.
+type tableInfo struct {
+	Name    string         `db:"name"`
+	Rows    sql.NullInt64  `db:"rows"`
+	Comment sql.NullString `db:"comment"`
+}
+
+// showTable returns the metadata of a table or view using the given show
+// command, e.g. SHOW TABLES or SHOW VIEWS.
+func showTable(ctx context.Context, db *sqlx.DB, show, dbName, schemaName, tableName string) (tableInfo, error) {
+	tables := []tableInfo{}
+	query := fmt.Sprintf("%s LIKE %s IN SCHEMA %s.%s", show, nameLikeLiteral(tableName), sqlIdent(dbName), sqlIdent(schemaName))
+	if err := db.SelectContext(ctx, &tables, query); err != nil {
+		return tableInfo{}, err
+	}
+	// LIKE is case insensitive and treats _ as a wildcard, so look for an
+	// exact match first.
+	for _, t := range tables {
+		if t.Name == tableName {
+			return t, nil
+		}
+	}
+	for _, t := range tables {
+		if strings.EqualFold(t.Name, tableName) {
+			return t, nil
+		}
+	}
+	return tableInfo{}, fmt.Errorf("Table %s not found", tableName)
+}
+
+// ddlObjectTypes are the object types supported by the get_ddl tool, mapped
+// to whether they live in a schema.
+var ddlObjectTypes = map[string]bool{
+	"database":  false,
+	"schema":    false,
+	"table":     true,
+	"view":      true,
+	"function":  true,
+	"procedure": true,
+	"sequence":  true,
+	"stage":     true,
+	"pipe":      true,
+	"task":      true,
+	"stream":    true,
+}
+
+// getDDL returns the DDL of an object using GET_DDL. name is the quoted,
+// qualified name of the object.
+func getDDL(ctx context.Context, db *sqlx.DB, objectType, name string) (string, error) {
+	var ddl string
+	if err := db.GetContext(ctx, &ddl, "SELECT GET_DDL(?, ?)", objectType, name); err != nil {
+		return "", fmt.Errorf("Failed to get DDL of %s %s: %w", objectType, name, err)
+	}
+	return ddl, nil
+}
+
+// describeObject returns the properties of an object as reported by the
+// describe command, e.g. DESCRIBE SEQUENCE, keyed by lowercase column name.
+func describeObject(ctx context.Context, db *sqlx.DB, describe, name string) (map[string]any, error) {
+	rows, err := db.QueryxContext(ctx, describe+" "+name)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to describe %s: %w", name, err)
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("Failed to describe %s: %w", name, err)
+		}
+		return nil, fmt.Errorf("%s not found", name)
+	}
+	props := map[string]any{}
+	if err := rows.MapScan(props); err != nil {
+		return nil, fmt.Errorf("Failed to scan rows: %v", err)
+	}
+	ret := map[string]any{}
+	for k, v := range props {
+		ret[strings.ToLower(k)] = v
+	}
+	return ret, nil
+}
+
+// schemaColumn is a column in a schema snapshot.
+type schemaColumn struct {
+	Name     string  `json:"name"`
+	Type     string  `json:"type"`
+	Nullable bool    `json:"nullable"`
+	Comment  *string `json:"comment,omitempty"`
+}
+
+// schemaTable is a table or view in a schema snapshot.
+type schemaTable struct {
+	Name    string         `json:"name"`
+	Type    string         `json:"type"`
+	Comment *string        `json:"comment,omitempty"`
+	Columns []schemaColumn `json:"columns"`
+}
+
+// describeSchema returns the tables of a schema along with their columns,
+// and views too if includeViews is set. Tables are left out once the
+// response would be larger than maxBytes, unless it is zero.
+func describeSchema(ctx context.Context, db *sqlx.DB, dbName, schemaName string, includeViews bool, maxBytes int) (map[string]any, error) {
+	infoSchema := quoteIdent(dbName) + ".INFORMATION_SCHEMA"
+	query := fmt.Sprintf(`SELECT c.TABLE_NAME, t.TABLE_TYPE, t.COMMENT AS TABLE_COMMENT, c.COLUMN_NAME, c.DATA_TYPE, c.IS_NULLABLE, c.COMMENT
+FROM %[1]s.COLUMNS c JOIN %[1]s.TABLES t ON t.TABLE_SCHEMA = c.TABLE_SCHEMA AND t.TABLE_NAME = c.TABLE_NAME
+WHERE c.TABLE_SCHEMA = ?`, infoSchema)
+	if !includeViews {
+		query += ` AND t.TABLE_TYPE NOT IN ('VIEW', 'MATERIALIZED VIEW')`
+	}
+	query += ` ORDER BY c.TABLE_NAME, c.ORDINAL_POSITION`
+	rows, err := db.QueryxContext(ctx, query, schemaName)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to describe schema %s.%s: %w", dbName, schemaName, err)
+	}
+	defer rows.Close()
+
+	tables := []*schemaTable{}
+	size, overBudget := 0, false
+	for rows.Next() {
+		var r struct {
+			Table        string  `db:"TABLE_NAME"`
+			TableType    string  `db:"TABLE_TYPE"`
+			TableComment *string `db:"TABLE_COMMENT"`
+			Column       string  `db:"COLUMN_NAME"`
+			Type         string  `db:"DATA_TYPE"`
+			Nullable     string  `db:"IS_NULLABLE"`
+			Comment      *string `db:"COMMENT"`
+		}
+		if err := rows.StructScan(&r); err != nil {
+			return nil, fmt.Errorf("Failed to scan rows: %v", err)
+		}
+		if len(tables) == 0 || tables[len(tables)-1].Name != r.Table {
+			tables = append(tables, &schemaTable{
+				Name:    r.Table,
+				Type:    r.TableType,
+				Comment: r.TableComment,
+				Columns: []schemaColumn{},
+			})
+		}
+		t := tables[len(tables)-1]
+		c := schemaColumn{
+			Name:     r.Column,
+			Type:     r.Type,
+			Nullable: r.Nullable == "YES",
+			Comment:  r.Comment,
+		}
+		t.Columns = append(t.Columns, c)
+		if maxBytes > 0 {
+			b, err := json.Marshal(c)
+			if err != nil {
+				return nil, fmt.Errorf("Failed to marshal column: %v", err)
+			}
+			if size += len(b); size > maxBytes {
+				// Drop the table rather than return part of its columns.
+				tables = tables[:len(tables)-1]
+				overBudget = true
+				break
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("Failed to fetch rows: %w", err)
+	}
+
+	result := map[string]any{
+		"database": dbName,
+		"schema":   schemaName,
+		"tables":   tables,
+	}
+	if overBudget {
+		result["notice"] = fmt.Sprintf("Only the first %d tables are included as the response would be larger than %d bytes", len(tables), maxBytes)
+	}
+	return result, nil
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// sqlWord is a top-level word of a statement along with its position.
+type sqlWord struct {
+	word       string
+	start, end int
+}
+
+// topLevelWords returns the words of stmt outside of parentheses, literals,
+// quoted identifiers and comments, in upper case.
+func topLevelWords(stmt string) ([]sqlWord, error) {
+	words := []sqlWord{}
+	depth := 0
+	for i := 0; i < len(stmt); {
+		c := stmt[i]
+		switch {
+		case c == '\'' || c == '"':
+			j := i + 1
+			for j < len(stmt) {
+				if stmt[j] == '\\' && c == '\'' {
+					j += 2
+					continue
+				}
+				if stmt[j] == c {
+					if j+1 < len(stmt) && stmt[j+1] == c {
+						j += 2
+						continue
+					}
+					break
+				}
+				j++
+			}
+			if j >= len(stmt) {
+				return nil, fmt.Errorf("Unterminated literal or quoted identifier")
+			}
+			i = j + 1
+		case strings.HasPrefix(stmt[i:], "$$"):
+			j := strings.Index(stmt[i+2:], "$$")
+			if j < 0 {
+				return nil, fmt.Errorf("Unterminated $$ literal")
+			}
+			i += j + 4
+		case strings.HasPrefix(stmt[i:], "--"), strings.HasPrefix(stmt[i:], "//"):
+			j := strings.IndexByte(stmt[i:], '\n')
+			if j < 0 {
+				i = len(stmt)
+			} else {
+				i += j + 1
+			}
+		case strings.HasPrefix(stmt[i:], "/*"):
+			j := strings.Index(stmt[i+2:], "*/")
+			if j < 0 {
+				return nil, fmt.Errorf("Unterminated comment")
+			}
+			i += j + 4
+		case c == '(':
+			depth++
+			i++
+		case c == ')':
+			depth--
+			i++
+		case isIdentByte(c):
+			j := i
+			for j < len(stmt) && isIdentByte(stmt[j]) {
+				j++
+			}
+			if depth == 0 {
+				words = append(words, sqlWord{word: strings.ToUpper(stmt[i:j]), start: i, end: j})
+			}
+			i = j
+		default:
+			i++
+		}
+	}
+	if depth != 0 {
+		return nil, fmt.Errorf("Unbalanced parentheses")
+	}
+	return words, nil
+}
+
+// dmlPreviewQuery transforms a DELETE or UPDATE statement into a SELECT of
+// the rows it would affect, from the table after DELETE FROM or UPDATE and
+// the WHERE clause. Statements joining other tables with DELETE ... USING or
+// UPDATE ... FROM are rejected since the rows they affect can't be selected
+// as simply.
+func dmlPreviewQuery(statement string) (string, error) {
+	statements := splitStatements(statement)
+	if len(statements) != 1 {
+		return "", newArgError("Provide a single DELETE or UPDATE statement")
+	}
+	stmt := statements[0]
+	words, err := topLevelWords(stmt)
+	if err != nil {
+		return "", newArgError("Failed to parse statement: %v", err)
+	}
+	find := func(from int, kws ...string) int {
+		for i := from; i < len(words); i++ {
+			for _, kw := range kws {
+				if words[i].word == kw {
+					return i
+				}
+			}
+		}
+		return -1
+	}
+
+	var target, where string
+	switch {
+	case len(words) >= 2 && words[0].word == "DELETE" && words[1].word == "FROM":
+		end := find(2, "USING", "WHERE")
+		if end >= 0 && words[end].word == "USING" {
+			return "", newArgError("DELETE ... USING can't be previewed, rewrite it as a SELECT joining the tables")
+		}
+		if end < 0 {
+			target = stmt[words[1].end:]
+		} else {
+			target = stmt[words[1].end:words[end].start]
+			where = stmt[words[end].end:]
+		}
+	case len(words) >= 1 && words[0].word == "UPDATE":
+		set := find(1, "SET")
+		if set < 0 {
+			return "", newArgError("Failed to parse statement: missing SET")
+		}
+		target = stmt[words[0].end:words[set].start]
+		end := find(set+1, "FROM", "WHERE")
+		if end >= 0 && words[end].word == "FROM" {
+			return "", newArgError("UPDATE ... FROM can't be previewed, rewrite it as a SELECT joining the tables")
+		}
+		if end >= 0 {
+			where = stmt[words[end].end:]
+		}
+	default:
+		return "", newArgError("Only DELETE and UPDATE statements can be previewed")
+	}
+	if target = strings.TrimSpace(target); target == "" {
+		return "", newArgError("Failed to parse statement: missing table")
+	}
+	// Clauses go on their own lines so that trailing comments don't swallow
+	// what follows.
+	query := "SELECT * FROM " + target
+	if where = strings.TrimRightFunc(where, unicode.IsSpace); strings.TrimSpace(where) != "" {
+		query += "\nWHERE" + where
+	}
+	return query, nil
+}
+
+// previewDML returns the number of rows a DELETE or UPDATE statement would
+// affect and up to limit of them, without running it.
+func previewDML(ctx context.Context, runner *queryRunner, statement string, limit int, args ...any) (map[string]any, error) {
+	query, err := dmlPreviewQuery(statement)
+	if err != nil {
+		return nil, err
+	}
+	var count int64
+	if err := runner.db.GetContext(ctx, &count, fmt.Sprintf("SELECT COUNT(*) FROM (\n%s\n)", query), args...); err != nil {
+		return nil, fmt.Errorf("Failed to count affected rows: %w", err)
+	}
+	result, err := runner.runQuery(ctx, fmt.Sprintf("%s\nLIMIT %d", query, limit), args...)
+	if err != nil {
+		return nil, err
+	}
+	result["affected_row_count"] = count
+	result["preview_query"] = query
+	return result, nil
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTopLevelWords(t *testing.T) {
+	tests := []struct {
+		stmt string
+		want []string
+	}{
+		{"delete from t where a = 1", []string{"DELETE", "FROM", "T", "WHERE", "A", "1"}},
+		{"SELECT f(x, y) FROM t", []string{"SELECT", "F", "FROM", "T"}},
+		{"UPDATE t SET a = 'where' -- where\nWHERE b", []string{"UPDATE", "T", "SET", "A", "WHERE", "B"}},
+		{`SELECT "from" /* from */ FROM $$from$$`, []string{"SELECT", "FROM"}},
+		{"SELECT 'it''s', 'a\\'b' x", []string{"SELECT", "X"}},
+	}
+	for _, tt := range tests {
+		words, err := topLevelWords(tt.stmt)
+		if err != nil {
+			t.Errorf("topLevelWords(%q) failed: %v", tt.stmt, err)
+			continue
+		}
+		got := []string{}
+		for _, w := range words {
+			got = append(got, w.word)
+			if w.word != strings.ToUpper(tt.stmt[w.start:w.end]) {
+				t.Errorf("topLevelWords(%q): %q is at %q", tt.stmt, w.word, tt.stmt[w.start:w.end])
+			}
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("topLevelWords(%q) = %q, want %q", tt.stmt, got, tt.want)
+		}
+	}
+}
+
+func TestTopLevelWordsInvalid(t *testing.T) {
+	for _, stmt := range []string{"SELECT 'a", `SELECT "a`, "SELECT $$a", "SELECT /* a", "SELECT (a", "SELECT a)"} {
+		if _, err := topLevelWords(stmt); err == nil {
+			t.Errorf("topLevelWords(%q) succeeded", stmt)
+		}
+	}
+}
+
+func TestDMLPreviewQuery(t *testing.T) {
+	tests := []struct {
+		stmt string
+		want string
+	}{
+		{"DELETE FROM db.s.t WHERE a = 1", "SELECT * FROM db.s.t\nWHERE a = 1"},
+		{"delete from db.s.t", "SELECT * FROM db.s.t"},
+		{"DELETE FROM db.s.t WHERE a IN (SELECT a FROM u WHERE b)", "SELECT * FROM db.s.t\nWHERE a IN (SELECT a FROM u WHERE b)"},
+		{"DELETE FROM db.s.t WHERE a = 1; ", "SELECT * FROM db.s.t\nWHERE a = 1"},
+		{"DELETE FROM db.s.t WHERE a = 1 -- done", "SELECT * FROM db.s.t\nWHERE a = 1 -- done"},
+		{"UPDATE db.s.t SET a = 1 WHERE b = 2", "SELECT * FROM db.s.t\nWHERE b = 2"},
+		{"UPDATE db.s.t SET a = (SELECT MAX(x) FROM u WHERE y)", "SELECT * FROM db.s.t"},
+		{"UPDATE db.s.t SET note = 'where' WHERE id = 3", "SELECT * FROM db.s.t\nWHERE id = 3"},
+	}
+	for _, tt := range tests {
+		got, err := dmlPreviewQuery(tt.stmt)
+		if err != nil {
+			t.Errorf("dmlPreviewQuery(%q) failed: %v", tt.stmt, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("dmlPreviewQuery(%q) = %q, want %q", tt.stmt, got, tt.want)
+		}
+	}
+}
+
+func TestDMLPreviewQueryRejected(t *testing.T) {
+	for _, stmt := range []string{
+		"SELECT * FROM t",
+		"INSERT INTO t VALUES (1)",
+		"DELETE FROM t USING u WHERE t.a = u.a",
+		"UPDATE t SET a = u.a FROM u WHERE t.b = u.b",
+		"UPDATE t",
+		"DELETE FROM WHERE a = 1",
+		"DELETE FROM t; DELETE FROM u",
+		"DELETE FROM t WHERE a = 'x",
+	} {
+		_, err := dmlPreviewQuery(stmt)
+		var argErr *argError
+		if !errors.As(err, &argErr) {
+			t.Errorf("dmlPreviewQuery(%q) = %v, want an argument error", stmt, err)
+		}
+	}
+}
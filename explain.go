@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// Formats supported by EXPLAIN.
+const (
+	explainText    = "text"
+	explainJSON    = "json"
+	explainTabular = "tabular"
+)
+
+// explainQuery returns the plan of query in the given format without
+// executing it. Tabular plans are returned as query results, while text and
+// JSON plans are returned as a single string.
+func explainQuery(ctx context.Context, runner *queryRunner, query, format string) (any, error) {
+	switch format {
+	case explainText, explainJSON, explainTabular:
+	default:
+		return nil, fmt.Errorf("Unsupported explain format %q", format)
+	}
+	explain := fmt.Sprintf("EXPLAIN USING %s %s", strings.ToUpper(format), query)
+	if format == explainTabular {
+		return runner.runQuery(ctx, explain)
+	}
+	// Text and JSON plans are fetched directly so that they aren't subject
+	// to cell truncation.
+	var plan string
+	if err := runner.db.GetContext(ctx, &plan, explain); err != nil {
+		return nil, fmt.Errorf("Failed to explain query: %w", err)
+	}
+	return plan, nil
+}
+
+// explainStats are the estimates found in Snowflake's JSON plans, both for
+// the whole query and for each table scan. Estimates missing from the plan
+// are left nil.
+type explainStats struct {
+	PartitionsTotal    *int64 `json:"partitionsTotal"`
+	PartitionsAssigned *int64 `json:"partitionsAssigned"`
+	BytesAssigned      *int64 `json:"bytesAssigned"`
+}
+
+// explainPlan is the part of a Snowflake JSON plan used to estimate cost.
+type explainPlan struct {
+	GlobalStats explainStats `json:"GlobalStats"`
+	Operations  [][]struct {
+		explainStats
+		Operation string   `json:"operation"`
+		Objects   []string `json:"objects"`
+	} `json:"Operations"`
+}
+
+// tableScanCost is the estimated cost of scanning a single table.
+type tableScanCost struct {
+	Table              string `json:"table"`
+	PartitionsTotal    *int64 `json:"partitions_total,omitempty"`
+	PartitionsAssigned *int64 `json:"partitions_assigned,omitempty"`
+	BytesAssigned      *int64 `json:"bytes_assigned,omitempty"`
+}
+
+// queryCost summarizes the estimated cost of a query from its plan.
+type queryCost struct {
+	PartitionsTotal    *int64 `json:"partitions_total,omitempty"`
+	PartitionsAssigned *int64 `json:"partitions_assigned,omitempty"`
+	BytesAssigned      *int64 `json:"bytes_assigned,omitempty"`
+	// PruningRatio is the fraction of partitions pruned away, when known.
+	PruningRatio *float64        `json:"pruning_ratio,omitempty"`
+	TableScans   []tableScanCost `json:"table_scans"`
+}
+
+// estimateQueryCost explains query and summarizes the partitions and bytes
+// it is estimated to scan, without executing it.
+func estimateQueryCost(ctx context.Context, runner *queryRunner, query string) (*queryCost, error) {
+	raw, err := explainQuery(ctx, runner, query, explainJSON)
+	if err != nil {
+		return nil, err
+	}
+	var plan explainPlan
+	if err := json.Unmarshal([]byte(raw.(string)), &plan); err != nil {
+		return nil, fmt.Errorf("Failed to parse query plan: %w", err)
+	}
+	cost := &queryCost{
+		PartitionsTotal:    plan.GlobalStats.PartitionsTotal,
+		PartitionsAssigned: plan.GlobalStats.PartitionsAssigned,
+		BytesAssigned:      plan.GlobalStats.BytesAssigned,
+		TableScans:         []tableScanCost{},
+	}
+	if t, a := cost.PartitionsTotal, cost.PartitionsAssigned; t != nil && a != nil && *t > 0 {
+		r := 1 - float64(*a)/float64(*t)
+		cost.PruningRatio = &r
+	}
+	for _, ops := range plan.Operations {
+		for _, op := range ops {
+			if op.Operation != "TableScan" {
+				continue
+			}
+			cost.TableScans = append(cost.TableScans, tableScanCost{
+				Table:              strings.Join(op.Objects, ", "),
+				PartitionsTotal:    op.PartitionsTotal,
+				PartitionsAssigned: op.PartitionsAssigned,
+				BytesAssigned:      op.BytesAssigned,
+			})
+		}
+	}
+	return cost, nil
+}
+
+// fullScanMinPartitions is the number of partitions from which a table is
+// considered large enough for a full scan to be worth a warning.
+const fullScanMinPartitions = 100
+
+// fullScanWarnings returns warnings for the tables in cost that are large and
+// scanned in full, without any partitions pruned.
+func fullScanWarnings(cost *queryCost) []string {
+	warnings := []string{}
+	for _, scan := range cost.TableScans {
+		t, a := scan.PartitionsTotal, scan.PartitionsAssigned
+		if t == nil || a == nil || *t < fullScanMinPartitions || *a < *t {
+			continue
+		}
+		w := fmt.Sprintf("All %d partitions of %s are scanned", *t, scan.Table)
+		if scan.BytesAssigned != nil {
+			w += fmt.Sprintf(" (%d bytes)", *scan.BytesAssigned)
+		}
+		warnings = append(warnings, w+". Filter on its clustering key or a date column to scan less")
+	}
+	return warnings
+}
+
+// addCostWarnings adds warnings about full scans of large tables by query to
+// result. Only single SELECT queries without bind parameters can be
+// explained. Failing to explain is logged and otherwise ignored since the
+// query already ran.
+func addCostWarnings(ctx context.Context, runner *queryRunner, result map[string]any, query string, args []any) {
+	if len(args) > 0 {
+		return
+	}
+	statements := splitStatements(query)
+	if len(statements) != 1 {
+		return
+	}
+	if kw := leadingKeyword(statements[0]); kw != "SELECT" && kw != "WITH" {
+		return
+	}
+	cost, err := estimateQueryCost(ctx, runner, statements[0])
+	if err != nil {
+		slog.Warn("Failed to check query for full table scans", "error", err)
+		return
+	}
+	if warnings := fullScanWarnings(cost); len(warnings) > 0 {
+		result["warnings"] = warnings
+	}
+}
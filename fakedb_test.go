@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// fakeResult is the canned result of a query run against a fakeDB.
+type fakeResult struct {
+	columns []string
+	// dbTypes are the Snowflake type names of the columns, e.g. FIXED.
+	dbTypes []string
+	rows    [][]driver.Value
+	err     error
+	// block makes the query run until its context is cancelled.
+	block bool
+}
+
+// fakeDB is a database/sql connector answering queries with canned results,
+// for testing without a Snowflake account.
+type fakeDB struct {
+	respond func(query string) fakeResult
+
+	mu      sync.Mutex
+	queries []string
+}
+
+// newFakeDB returns a database whose queries are answered by respond, along
+// with the fakeDB recording the queries run.
+func newFakeDB(t *testing.T, respond func(query string) fakeResult) (*sqlx.DB, *fakeDB) {
+	f := &fakeDB{respond: respond}
+	db := sqlx.NewDb(sql.OpenDB(f), "snowflake").Unsafe()
+	t.Cleanup(func() { db.Close() })
+	return db, f
+}
+
+// ran returns the queries run so far.
+func (f *fakeDB) ran() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string{}, f.queries...)
+}
+
+func (f *fakeDB) Connect(context.Context) (driver.Conn, error) { return &fakeConn{f}, nil }
+func (f *fakeDB) Driver() driver.Driver                        { return fakeDriver{} }
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) {
+	return nil, errors.New("fakeDriver can only be used through fakeDB")
+}
+
+type fakeConn struct{ f *fakeDB }
+
+func (c *fakeConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("Prepared statements are not supported")
+}
+func (c *fakeConn) Close() error { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("Transactions are not supported")
+}
+
+func (c *fakeConn) run(ctx context.Context, query string) (fakeResult, error) {
+	c.f.mu.Lock()
+	c.f.queries = append(c.f.queries, query)
+	c.f.mu.Unlock()
+	r := c.f.respond(query)
+	if r.block {
+		<-ctx.Done()
+		return r, ctx.Err()
+	}
+	return r, r.err
+}
+
+func (c *fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	r, err := c.run(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return &fakeRows{result: r}, nil
+}
+
+func (c *fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	r, err := c.run(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(len(r.rows)), nil
+}
+
+type fakeRows struct {
+	result fakeResult
+	next   int
+}
+
+func (r *fakeRows) Columns() []string { return r.result.columns }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.next >= len(r.result.rows) {
+		return io.EOF
+	}
+	copy(dest, r.result.rows[r.next])
+	r.next++
+	return nil
+}
+
+func (r *fakeRows) ColumnTypeDatabaseTypeName(i int) string {
+	if i < len(r.result.dbTypes) {
+		return r.result.dbTypes[i]
+	}
+	return "TEXT"
+}
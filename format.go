@@ -0,0 +1,153 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Output formats of query results.
+const (
+	formatJSON     = "json"
+	formatMarkdown = "markdown"
+	formatArrow    = "arrow"
+)
+
+// formatOptions are options of the output formats.
+type formatOptions struct {
+	// compact omits indentation.
+	compact bool
+	// rowsOnly returns just the rows as JSON lines, one array per row,
+	// followed by the notice, if any, as separate content.
+	rowsOnly bool
+	// nullString renders NULLs in the markdown format. JSON always uses
+	// null.
+	nullString string
+	// structured appends the result in the shape of structuredResultSchema.
+	structured bool
+}
+
+// formatToolResult returns a query result from queryRunner.runQuery in the
+// given format.
+func formatToolResult(result map[string]any, format string, opts formatOptions) (*mcp.CallToolResult, error) {
+	res, err := formatResult(result, format, opts)
+	if err != nil || !opts.structured {
+		return res, err
+	}
+	if err := addStructuredResult(res, result); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// formatResult renders result for formatToolResult.
+func formatResult(result map[string]any, format string, opts formatOptions) (*mcp.CallToolResult, error) {
+	switch format {
+	case "", formatJSON:
+		if opts.rowsOnly {
+			return rowsToolResult(result)
+		}
+		if opts.compact {
+			return compactJSONToolResult(result)
+		}
+		return jsonToolResult(result)
+	case formatMarkdown:
+		// Results of multiple statements or result sets are rendered one
+		// after another.
+		if results, ok := result["results"].([]map[string]any); ok {
+			tables := make([]string, len(results))
+			for i, r := range results {
+				tables[i] = fmt.Sprintf("Result %d:\n\n%s", i+1, markdownTable(r, opts.nullString))
+			}
+			return mcp.NewToolResultText(strings.Join(tables, "\n")), nil
+		}
+		return mcp.NewToolResultText(markdownTable(result, opts.nullString)), nil
+	}
+	return nil, fmt.Errorf("Unsupported format %q", format)
+}
+
+// rowsToolResult returns the rows of a query result as JSON lines.
+func rowsToolResult(result map[string]any) (*mcp.CallToolResult, error) {
+	if _, ok := result["results"]; ok {
+		return nil, newArgError("Rows only is not supported for statements returning several result sets")
+	}
+	rows, _ := result["rows"].([][]any)
+	b := &strings.Builder{}
+	for _, row := range rows {
+		line, err := json.Marshal(row)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to marshal row: %v", err)
+		}
+		b.Write(line)
+		b.WriteString("\n")
+	}
+	res := mcp.NewToolResultText(b.String())
+	if notice, ok := result["notice"].(string); ok {
+		res.Content = append(res.Content, mcp.NewTextContent(notice))
+	}
+	if warnings, ok := result["warnings"].([]string); ok {
+		res.Content = append(res.Content, mcp.NewTextContent("Warning: "+strings.Join(warnings, "\nWarning: ")))
+	}
+	return res, nil
+}
+
+// markdownTable renders a query result as a GitHub flavored Markdown table
+// followed by the notice and warnings, if any. NULLs are rendered as nullString.
+func markdownTable(result map[string]any, nullString string) string {
+	columnInfo, _ := result["column_info"].([]map[string]any)
+	rows, _ := result["rows"].([][]any)
+
+	b := &strings.Builder{}
+	b.WriteString("|")
+	for _, c := range columnInfo {
+		fmt.Fprintf(b, " %s |", markdownCell(c["name"], nullString))
+	}
+	b.WriteString("\n|")
+	for range columnInfo {
+		b.WriteString(" --- |")
+	}
+	b.WriteString("\n")
+	for _, row := range rows {
+		b.WriteString("|")
+		for _, v := range row {
+			fmt.Fprintf(b, " %s |", markdownCell(v, nullString))
+		}
+		b.WriteString("\n")
+	}
+	if notice, ok := result["notice"].(string); ok {
+		fmt.Fprintf(b, "\n%s\n", notice)
+	}
+	if warnings, ok := result["warnings"].([]string); ok {
+		b.WriteString("\n")
+		for _, w := range warnings {
+			fmt.Fprintf(b, "Warning: %s\n", w)
+		}
+	}
+	return b.String()
+}
+
+// markdownCellReplacer escapes characters that would break a table row.
+var markdownCellReplacer = strings.NewReplacer(
+	`\`, `\\`,
+	"|", `\|`,
+	"\r\n", "<br>",
+	"\n", "<br>",
+	"\r", "<br>",
+)
+
+func markdownCell(v any, nullString string) string {
+	switch v := v.(type) {
+	case nil:
+		return markdownCellReplacer.Replace(nullString)
+	case []byte:
+		return markdownCellReplacer.Replace(fmt.Sprintf("%x", v))
+	case map[string]any, []any:
+		// Semi-structured values are shown as JSON.
+		if b, err := json.Marshal(v); err == nil {
+			return markdownCellReplacer.Replace(string(b))
+		}
+	}
+	return markdownCellReplacer.Replace(fmt.Sprint(v))
+}
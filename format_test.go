@@ -0,0 +1,147 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestMarkdownCell(t *testing.T) {
+	tests := []struct {
+		v          any
+		nullString string
+		want       string
+	}{
+		{nil, "", ""},
+		{nil, "NULL", "NULL"},
+		{nil, "a|b", `a\|b`},
+		{"", "NULL", ""},
+		{"a|b", "", `a\|b`},
+		{`back\slash`, "", `back\\slash`},
+		{"two\nlines\r\nand\rmore", "", "two<br>lines<br>and<br>more"},
+		{[]byte{0xde, 0xad}, "", "dead"},
+		{map[string]any{"a": []any{1, "|"}}, "", `{"a":[1,"\|"]}`},
+		{[]any{}, "", "[]"},
+		{int64(42), "", "42"},
+		{true, "", "true"},
+	}
+	for _, tt := range tests {
+		if got := markdownCell(tt.v, tt.nullString); got != tt.want {
+			t.Errorf("markdownCell(%#v, %q) = %q, want %q", tt.v, tt.nullString, got, tt.want)
+		}
+	}
+}
+
+func TestMarkdownTable(t *testing.T) {
+	result := map[string]any{
+		"column_info": []map[string]any{{"name": "ID"}, {"name": "NOTE"}},
+		"rows":        [][]any{{"1", "a|b"}, {"2", nil}},
+		"notice":      "Only first 2 rows are shown",
+		"warnings":    []string{"Full scan of T"},
+	}
+	want := "| ID | NOTE |\n" +
+		"| --- | --- |\n" +
+		"| 1 | a\\|b |\n" +
+		"| 2 | NULL |\n" +
+		"\nOnly first 2 rows are shown\n" +
+		"\nWarning: Full scan of T\n"
+	if got := markdownTable(result, "NULL"); got != want {
+		t.Errorf("markdownTable =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestMarkdownTableEmpty(t *testing.T) {
+	result := map[string]any{
+		"column_info": []map[string]any{{"name": "ID"}},
+		"rows":        [][]any{},
+	}
+	if got, want := markdownTable(result, ""), "| ID |\n| --- |\n"; got != want {
+		t.Errorf("markdownTable = %q, want %q", got, want)
+	}
+}
+
+// toolText returns the text contents of a tool result.
+func toolText(t *testing.T, res *mcp.CallToolResult) []string {
+	texts := []string{}
+	for _, c := range res.Content {
+		texts = append(texts, c.(mcp.TextContent).Text)
+	}
+	return texts
+}
+
+func TestFormatToolResultNulls(t *testing.T) {
+	result := map[string]any{
+		"column_info": []map[string]any{{"name": "A"}, {"name": "B"}},
+		"rows":        [][]any{{nil, "x"}},
+	}
+	tests := []struct {
+		format string
+		opts   formatOptions
+		want   string
+	}{
+		// JSON always uses null, whatever the null string.
+		{formatJSON, formatOptions{nullString: "NULL", compact: true}, `{"column_info":[{"name":"A"},{"name":"B"}],"rows":[[null,"x"]]}`},
+		{formatJSON, formatOptions{nullString: "NULL", rowsOnly: true}, "[null,\"x\"]\n"},
+		{formatMarkdown, formatOptions{}, "| A | B |\n| --- | --- |\n|  | x |\n"},
+		{formatMarkdown, formatOptions{nullString: "NULL"}, "| A | B |\n| --- | --- |\n| NULL | x |\n"},
+	}
+	for _, tt := range tests {
+		res, err := formatToolResult(result, tt.format, tt.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := toolText(t, res); len(got) != 1 || got[0] != tt.want {
+			t.Errorf("%s with %+v = %q, want %q", tt.format, tt.opts, got, tt.want)
+		}
+	}
+}
+
+func TestFormatToolResultStructured(t *testing.T) {
+	single := map[string]any{
+		"column_info": []map[string]any{{"name": "A", "type": "NUMBER"}},
+		"rows":        [][]any{{"1"}},
+		"row_count":   1,
+		"notice":      "Only first 1 rows are shown",
+		"query_id":    "01b2c3d4-0000-0000-0000-000000000001",
+		"elapsed_ms":  int64(5),
+	}
+	multi := map[string]any{
+		"results": []map[string]any{
+			{"column_info": []map[string]any{{"name": "status", "type": "TEXT"}}, "rows": [][]any{{"ok"}}, "row_count": 1},
+			{"column_info": []map[string]any{}, "rows": [][]any{}, "row_count": 0},
+		},
+		"warnings": []string{"Full scan of T"},
+	}
+	tests := []struct {
+		result map[string]any
+		format string
+		want   string
+	}{
+		{single, formatJSON, `{"query_id":"01b2c3d4-0000-0000-0000-000000000001","results":[{"columns":[{"name":"A","type":"NUMBER"}],"notice":"Only first 1 rows are shown","row_count":1,"rows":[["1"]]}]}`},
+		{single, formatMarkdown, `{"query_id":"01b2c3d4-0000-0000-0000-000000000001","results":[{"columns":[{"name":"A","type":"NUMBER"}],"notice":"Only first 1 rows are shown","row_count":1,"rows":[["1"]]}]}`},
+		{multi, formatMarkdown, `{"results":[{"columns":[{"name":"status","type":"TEXT"}],"row_count":1,"rows":[["ok"]]},{"columns":[],"row_count":0,"rows":[]}],"warnings":["Full scan of T"]}`},
+	}
+	for _, tt := range tests {
+		plain, err := formatToolResult(tt.result, tt.format, formatOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := formatToolResult(tt.result, tt.format, formatOptions{structured: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := toolText(t, res)
+		if want := len(toolText(t, plain)) + 1; len(got) != want {
+			t.Fatalf("%s result has %d contents, want %d", tt.format, len(got), want)
+		}
+		if got[len(got)-1] != tt.want {
+			t.Errorf("Structured %s result is %s, want %s", tt.format, got[len(got)-1], tt.want)
+		}
+	}
+
+	var schema map[string]any
+	if err := json.Unmarshal([]byte(structuredResultSchema), &schema); err != nil {
+		t.Errorf("Invalid schema: %v", err)
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"fmt"
+)
+
+// grantObjectTypes are the object types supported by the show_grants tool,
+// mapped to whether they live in a schema.
+var grantObjectTypes = map[string]bool{
+	"database":  false,
+	"schema":    false,
+	"warehouse": false,
+	"table":     true,
+	"view":      true,
+	"function":  true,
+	"procedure": true,
+	"sequence":  true,
+	"stage":     true,
+	"pipe":      true,
+	"task":      true,
+	"stream":    true,
+}
+
+// showGrantsOn returns the privileges granted on an object. name is the
+// quoted, qualified name of the object.
+func showGrantsOn(ctx context.Context, runner *queryRunner, objectType, name string) (map[string]any, error) {
+	return runner.runQuery(ctx, fmt.Sprintf("SHOW GRANTS ON %s %s", objectType, name))
+}
+
+// showGrantsToRole returns the privileges granted to a role.
+func showGrantsToRole(ctx context.Context, runner *queryRunner, role string) (map[string]any, error) {
+	ident, err := parseIdent(role)
+	if err != nil {
+		return nil, err
+	}
+	return runner.runQuery(ctx, "SHOW GRANTS TO ROLE "+quoteIdent(ident))
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// queryHistoryColumns are the columns returned by the query_history tool,
+// present in both ACCOUNT_USAGE.QUERY_HISTORY and the INFORMATION_SCHEMA
+// table function.
+const queryHistoryColumns = "QUERY_ID, QUERY_TEXT, EXECUTION_STATUS, ERROR_MESSAGE, START_TIME, END_TIME, TOTAL_ELAPSED_TIME, BYTES_SCANNED, ROWS_PRODUCED, WAREHOUSE_NAME"
+
+// queryHistory returns up to limit of the most recent queries of the current
+// user, optionally started within the given time range. It uses
+// SNOWFLAKE.ACCOUNT_USAGE.QUERY_HISTORY, falling back to the
+// INFORMATION_SCHEMA.QUERY_HISTORY_BY_USER table function when the role
+// can't access account usage.
+func queryHistory(ctx context.Context, runner *queryRunner, limit int, startTime, endTime string) (map[string]any, error) {
+	where := []string{"USER_NAME = CURRENT_USER()"}
+	args := []any{}
+	if startTime != "" {
+		where = append(where, "START_TIME >= TO_TIMESTAMP_LTZ(?)")
+		args = append(args, startTime)
+	}
+	if endTime != "" {
+		where = append(where, "START_TIME <= TO_TIMESTAMP_LTZ(?)")
+		args = append(args, endTime)
+	}
+	result, err := runner.runQuery(ctx, fmt.Sprintf(
+		`SELECT %s FROM SNOWFLAKE.ACCOUNT_USAGE.QUERY_HISTORY WHERE %s ORDER BY START_TIME DESC LIMIT %d`,
+		queryHistoryColumns, strings.Join(where, " AND "), limit,
+	), args...)
+	if err == nil {
+		result["source"] = "SNOWFLAKE.ACCOUNT_USAGE.QUERY_HISTORY, which may lag behind by up to 45 minutes"
+		return result, nil
+	}
+	slog.Info("Falling back to INFORMATION_SCHEMA for query history", "error", err)
+
+	// The table function filters by time itself and needs a current database
+	// to resolve INFORMATION_SCHEMA.
+	fnArgs := []string{"USER_NAME => CURRENT_USER()", fmt.Sprintf("RESULT_LIMIT => %d", limit)}
+	if startTime != "" {
+		fnArgs = append(fnArgs, "END_TIME_RANGE_START => TO_TIMESTAMP_LTZ(?)")
+	}
+	if endTime != "" {
+		fnArgs = append(fnArgs, "END_TIME_RANGE_END => TO_TIMESTAMP_LTZ(?)")
+	}
+	result, fallbackErr := runner.runQuery(ctx, fmt.Sprintf(
+		`SELECT %s FROM TABLE(INFORMATION_SCHEMA.QUERY_HISTORY_BY_USER(%s)) ORDER BY START_TIME DESC LIMIT %d`,
+		queryHistoryColumns, strings.Join(fnArgs, ", "), limit,
+	), args...)
+	if fallbackErr != nil {
+		return nil, fmt.Errorf("Failed to get query history from ACCOUNT_USAGE (%v) or INFORMATION_SCHEMA, which requires a current database: %w", err, fallbackErr)
+	}
+	result["source"] = "INFORMATION_SCHEMA.QUERY_HISTORY_BY_USER, which covers the last 7 days"
+	return result, nil
+}
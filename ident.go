@@ -0,0 +1,216 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// quoteIdentifiers makes names in resource URIs and tool arguments case
+// sensitive, as if they were quoted, instead of resolving unquoted names in
+// upper case like Snowflake does. It is set from -quote-identifiers on
+// startup.
+var quoteIdentifiers bool
+
+// quoteIdent quotes name as a Snowflake identifier, preserving its case.
+func quoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// splitQualifiedName splits a dot separated object name such as
+// db.schema."My Table" into its parts. Unquoted parts are uppercased as
+// Snowflake does, unless quoteIdentifiers is set, and quoted parts are
+// unescaped and kept as is.
+func splitQualifiedName(name string) ([]string, error) {
+	return splitName(name, !quoteIdentifiers)
+}
+
+// splitName is like splitQualifiedName but only uppercases unquoted parts if
+// upper is set.
+func splitName(name string, upper bool) ([]string, error) {
+	parts := []string{}
+	for i := 0; ; {
+		var part string
+		if i < len(name) && name[i] == '"' {
+			b := strings.Builder{}
+			i++
+			for {
+				if i >= len(name) {
+					return nil, newArgError("Unterminated quoted identifier in %q", name)
+				}
+				if name[i] == '"' {
+					if i+1 < len(name) && name[i+1] == '"' {
+						b.WriteByte('"')
+						i += 2
+						continue
+					}
+					i++
+					break
+				}
+				b.WriteByte(name[i])
+				i++
+			}
+			part = b.String()
+		} else {
+			j := strings.IndexByte(name[i:], '.')
+			if j < 0 {
+				j = len(name) - i
+			}
+			part = strings.TrimSpace(name[i : i+j])
+			if upper {
+				part = strings.ToUpper(part)
+			}
+			i += j
+		}
+		if part == "" {
+			return nil, newArgError("Empty identifier in %q", name)
+		}
+		parts = append(parts, part)
+		if i >= len(name) {
+			return parts, nil
+		}
+		if name[i] != '.' {
+			return nil, newArgError("Invalid identifier %q", name)
+		}
+		i++
+	}
+}
+
+// parseIdent parses a single, possibly quoted, identifier.
+func parseIdent(name string) (string, error) {
+	parts, err := splitQualifiedName(name)
+	if err != nil {
+		return "", err
+	}
+	if len(parts) != 1 {
+		return "", newArgError("Invalid identifier %q", name)
+	}
+	return parts[0], nil
+}
+
+// parseTableName parses a fully qualified database.schema.table name.
+func parseTableName(name string) (dbName, schemaName, tableName string, err error) {
+	parts, err := splitQualifiedName(name)
+	if err != nil {
+		return "", "", "", err
+	}
+	if len(parts) != 3 {
+		return "", "", "", newArgError("Table name %q must be fully qualified as database.schema.table", name)
+	}
+	return parts[0], parts[1], parts[2], nil
+}
+
+// quoteTableName returns the quoted fully qualified name of a table.
+func quoteTableName(dbName, schemaName, tableName string) string {
+	return quoteIdent(dbName) + "." + quoteIdent(schemaName) + "." + quoteIdent(tableName)
+}
+
+// argTypePat matches a data type in the signature of a function or
+// procedure, e.g. NUMBER, VARCHAR(100), NUMBER(38, 0) or DOUBLE PRECISION.
+var argTypePat = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*(?: +[A-Za-z][A-Za-z0-9_]*)*(?: *\( *[A-Za-z0-9_]+(?: *, *[A-Za-z0-9_]+)? *\))?$`)
+
+// parseSignature parses the signature of a function or procedure such as
+// my_func(NUMBER, VARCHAR) and returns it with the name quoted and the
+// argument types uppercased.
+func parseSignature(sig string) (string, error) {
+	invalid := newArgError("Invalid signature %q, must be the name followed by the argument types, e.g. my_func(NUMBER, VARCHAR)", sig)
+	sig = strings.TrimSpace(sig)
+	// The parenthesis opening the argument types follows the name, which
+	// may be quoted and contain parentheses itself.
+	from := 0
+	if strings.HasPrefix(sig, `"`) {
+		for i := 1; i < len(sig); i++ {
+			if sig[i] == '"' {
+				if i+1 < len(sig) && sig[i+1] == '"' {
+					i++
+					continue
+				}
+				from = i + 1
+				break
+			}
+		}
+	}
+	open := strings.IndexByte(sig[from:], '(')
+	if open < 0 || !strings.HasSuffix(sig, ")") {
+		return "", invalid
+	}
+	open += from
+	name, err := parseIdent(strings.TrimSpace(sig[:open]))
+	if err != nil {
+		return "", invalid
+	}
+	types := []string{}
+	args := strings.TrimSpace(sig[open+1 : len(sig)-1])
+	if args != "" {
+		depth, start := 0, 0
+		for i := 0; i <= len(args); i++ {
+			if i < len(args) && (args[i] != ',' || depth > 0) {
+				switch args[i] {
+				case '(':
+					depth++
+				case ')':
+					depth--
+				}
+				continue
+			}
+			t := strings.TrimSpace(args[start:i])
+			if !argTypePat.MatchString(t) {
+				return "", newArgError("Invalid argument type %q in signature %q", t, sig)
+			}
+			types = append(types, strings.ToUpper(t))
+			start = i + 1
+		}
+	}
+	return quoteIdent(name) + "(" + strings.Join(types, ", ") + ")", nil
+}
+
+// qualifiedObjectName returns the quoted, qualified name of an object of
+// objectType from its database, schema and name as given to tools. Databases
+// and warehouses are named on their own, other objects are qualified with
+// their database, and with their schema too if inSchema is set. Function and
+// procedure names carry their signature, see parseSignature. The database
+// must be allowed.
+func qualifiedObjectName(allowed databaseAllowlist, objectType string, inSchema bool, dbName, schemaName, name string) (string, error) {
+	parts := []string{}
+	switch objectType {
+	case "database":
+		if err := allowed.checkIdent(name); err != nil {
+			return "", err
+		}
+	case "warehouse":
+	default:
+		if dbName == "" {
+			return "", newArgError("Database is required for object type %s", objectType)
+		}
+		if err := allowed.checkIdent(dbName); err != nil {
+			return "", err
+		}
+		parts = append(parts, dbName)
+	}
+	if inSchema {
+		if schemaName == "" {
+			return "", newArgError("Schema is required for object type %s", objectType)
+		}
+		parts = append(parts, schemaName)
+	}
+	for i, p := range parts {
+		ident, err := parseIdent(p)
+		if err != nil {
+			return "", err
+		}
+		parts[i] = quoteIdent(ident)
+	}
+	if objectType == "function" || objectType == "procedure" {
+		sig, err := parseSignature(name)
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, sig)
+	} else {
+		ident, err := parseIdent(name)
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, quoteIdent(ident))
+	}
+	return strings.Join(parts, "."), nil
+}
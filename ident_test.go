@@ -0,0 +1,56 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseSignature(t *testing.T) {
+	tests := []struct {
+		sig  string
+		want string
+	}{
+		{"my_func(NUMBER, VARCHAR)", `"MY_FUNC"(NUMBER, VARCHAR)`},
+		{"my_func()", `"MY_FUNC"()`},
+		{" my_func ( number,varchar ) ", `"MY_FUNC"(NUMBER, VARCHAR)`},
+		{"f(NUMBER(38, 0), VARCHAR(100))", `"F"(NUMBER(38, 0), VARCHAR(100))`},
+		{"f(DOUBLE PRECISION, TIMESTAMP_NTZ(9))", `"F"(DOUBLE PRECISION, TIMESTAMP_NTZ(9))`},
+		{`"my (odd) func"(ARRAY)`, `"my (odd) func"(ARRAY)`},
+		{`"a""b"(OBJECT)`, `"a""b"(OBJECT)`},
+	}
+	for _, tt := range tests {
+		got, err := parseSignature(tt.sig)
+		if err != nil || got != tt.want {
+			t.Errorf("parseSignature(%q) = %q, %v, want %q", tt.sig, got, err, tt.want)
+		}
+	}
+
+	for _, sig := range []string{
+		"my_func",
+		"my_func(NUMBER",
+		"(NUMBER)",
+		"a.b(NUMBER)",
+		"f(NUMBER) RETURNS NUMBER",
+		"f(NUMBER); DROP TABLE t",
+		"f(NUMBER); DROP TABLE t(x)",
+		"f(NUMBER, )",
+		"f(NUMBER))",
+		"f('x')",
+		"f(NUMBER -- )",
+		"f(VARCHAR(1); DROP TABLE t)",
+		`"f(NUMBER)`,
+	} {
+		_, err := parseSignature(sig)
+		var argErr *argError
+		if !errors.As(err, &argErr) {
+			t.Errorf("parseSignature(%q) = %v, want an argument error", sig, err)
+		}
+	}
+}
+
+func TestQualifiedObjectNameFunction(t *testing.T) {
+	got, err := qualifiedObjectName(nil, "function", true, "db", "public", "f(NUMBER)")
+	if want := `"DB"."PUBLIC"."F"(NUMBER)`; err != nil || got != want {
+		t.Errorf("qualifiedObjectName = %q, %v, want %q", got, err, want)
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// accountInfo is the version, region and edition of the Snowflake account.
+type accountInfo struct {
+	Version      string `db:"VERSION" json:"version"`
+	Region       string `db:"REGION" json:"region"`
+	Organization string `db:"ORGANIZATION" json:"organization"`
+	Account      string `db:"ACCOUNT" json:"account"`
+	// Edition is only available to roles that can list organization
+	// accounts.
+	Edition string `db:"-" json:"edition,omitempty"`
+}
+
+// getAccountInfo returns the version, region and, if visible to the role,
+// edition of the account.
+func getAccountInfo(ctx context.Context, db *sqlx.DB) (accountInfo, error) {
+	info := accountInfo{}
+	if err := db.GetContext(ctx, &info, `SELECT CURRENT_VERSION() AS "VERSION", CURRENT_REGION() AS "REGION", CURRENT_ORGANIZATION_NAME() AS "ORGANIZATION", CURRENT_ACCOUNT_NAME() AS "ACCOUNT"`); err != nil {
+		return info, fmt.Errorf("Failed to get account info: %w", err)
+	}
+
+	accounts := []struct {
+		Name    string `db:"account_name"`
+		Edition string `db:"edition"`
+	}{}
+	query := "SHOW ORGANIZATION ACCOUNTS LIKE " + nameLikeLiteral(info.Account)
+	if err := db.SelectContext(ctx, &accounts, query); err != nil {
+		slog.Debug("Failed to get account edition", "error", err)
+		return info, nil
+	}
+	for _, a := range accounts {
+		if strings.EqualFold(a.Name, info.Account) {
+			info.Edition = a.Edition
+		}
+	}
+	return info, nil
+}
+
+// addInfoResource registers the snowflake://info resource. Its contents are
+// fetched once and kept for the lifetime of the server as they rarely
+// change. It shadows the schema listing of a database named info.
+func addInfoResource(s *server.MCPServer, db *sqlx.DB) {
+	var (
+		mu       sync.Mutex
+		contents []mcp.ResourceContents
+	)
+	s.AddResource(mcp.NewResource(
+		"snowflake://info",
+		"Account info",
+		mcp.WithResourceDescription("Snowflake version, region and edition of the account"),
+		mcp.WithMIMEType("application/json"),
+	), func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if contents != nil {
+			return contents, nil
+		}
+		info, err := getAccountInfo(ctx, db)
+		if err != nil {
+			return nil, explainAuthError(err)
+		}
+		if contents, err = jsonResourceContents(request.Params.URI, info); err != nil {
+			return nil, err
+		}
+		return contents, nil
+	})
+}
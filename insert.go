@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// insertColumn is a column to provide a value for in an INSERT statement.
+type insertColumn struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Nullable bool   `json:"nullable"`
+	// HasDefault is whether the column may be left out.
+	HasDefault bool `json:"has_default,omitempty"`
+}
+
+// isGeneratedColumn reports whether the values of c are generated by
+// Snowflake, e.g. identity and computed columns, so shouldn't be inserted.
+func isGeneratedColumn(c tableColumn) bool {
+	if c.Expression != nil && *c.Expression != "" {
+		return true
+	}
+	if c.Default == nil {
+		return false
+	}
+	d := strings.ToUpper(*c.Default)
+	return strings.HasPrefix(d, "IDENTITY") || strings.HasPrefix(d, "AUTOINCREMENT") || strings.HasSuffix(d, ".NEXTVAL")
+}
+
+// insertTemplate returns a parameterized INSERT statement for a table with
+// a ? placeholder per column, leaving out generated columns.
+func insertTemplate(ctx context.Context, db *sqlx.DB, dbName, schemaName, tableName string) (map[string]any, error) {
+	table := quoteTableName(dbName, schemaName, tableName)
+	columns, err := describeTable(ctx, db, table)
+	if err != nil {
+		return nil, err
+	}
+	names := []string{}
+	insertColumns := []insertColumn{}
+	skipped := []string{}
+	for _, c := range columns {
+		if isGeneratedColumn(c) {
+			skipped = append(skipped, c.Name)
+			continue
+		}
+		names = append(names, quoteIdent(c.Name))
+		insertColumns = append(insertColumns, insertColumn{
+			Name:       c.Name,
+			Type:       c.Type,
+			Nullable:   c.Null == "Y",
+			HasDefault: c.Default != nil,
+		})
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(names)), ", ")
+	return map[string]any{
+		"statement":         "INSERT INTO " + table + " (" + strings.Join(names, ", ") + ") VALUES (" + placeholders + ")",
+		"columns":           insertColumns,
+		"generated_columns": skipped,
+	}, nil
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// maxJoinPreviewRows caps the number of rows returned by a join preview.
+const maxJoinPreviewRows = 100
+
+// previewJoin runs a sample join between two fully qualified tables. When the
+// join columns are not given, they are detected from foreign keys between the
+// tables.
+func previewJoin(ctx context.Context, runner *queryRunner, leftTable, rightTable, leftColumn, rightColumn string, limit int) (map[string]any, error) {
+	ldb, lschema, ltable, err := parseTableName(leftTable)
+	if err != nil {
+		return nil, err
+	}
+	rdb, rschema, rtable, err := parseTableName(rightTable)
+	if err != nil {
+		return nil, err
+	}
+	left := quoteTableName(ldb, lschema, ltable)
+	right := quoteTableName(rdb, rschema, rtable)
+
+	for _, c := range []*string{&leftColumn, &rightColumn} {
+		if *c == "" {
+			continue
+		}
+		if *c, err = parseIdent(*c); err != nil {
+			return nil, err
+		}
+	}
+
+	switch {
+	case leftColumn == "" && rightColumn == "":
+		leftColumn, rightColumn, err = findForeignKey(ctx, runner.db, left, right)
+		if err != nil {
+			return nil, err
+		}
+	case leftColumn == "":
+		leftColumn = rightColumn
+	case rightColumn == "":
+		rightColumn = leftColumn
+	}
+
+	condition := fmt.Sprintf("l.%s = r.%s", quoteIdent(leftColumn), quoteIdent(rightColumn))
+	result, err := runner.runQuery(ctx, fmt.Sprintf(
+		"SELECT * FROM %s AS l JOIN %s AS r ON %s LIMIT %d", left, right, condition, limit,
+	))
+	if err != nil {
+		return nil, err
+	}
+	result["join_condition"] = fmt.Sprintf("%s.%s = %s.%s", left, quoteIdent(leftColumn), right, quoteIdent(rightColumn))
+	return result, nil
+}
+
+// findForeignKey looks for a single column foreign key relationship between
+// two quoted table names in either direction and returns the join columns.
+func findForeignKey(ctx context.Context, db *sqlx.DB, left, right string) (leftColumn, rightColumn string, err error) {
+	type importedKey struct {
+		PKDatabase string `db:"pk_database_name"`
+		PKSchema   string `db:"pk_schema_name"`
+		PKTable    string `db:"pk_table_name"`
+		PKColumn   string `db:"pk_column_name"`
+		FKColumn   string `db:"fk_column_name"`
+	}
+
+	lookup := func(fkTable, pkTable string) (string, string, error) {
+		keys := []importedKey{}
+		if err := db.SelectContext(ctx, &keys, "SHOW IMPORTED KEYS IN TABLE "+fkTable); err != nil {
+			return "", "", fmt.Errorf("Failed to get foreign keys of %s: %w", fkTable, err)
+		}
+		for _, k := range keys {
+			if quoteTableName(k.PKDatabase, k.PKSchema, k.PKTable) == pkTable {
+				return k.FKColumn, k.PKColumn, nil
+			}
+		}
+		return "", "", nil
+	}
+
+	fk, pk, err := lookup(left, right)
+	if err != nil {
+		return "", "", err
+	}
+	if fk != "" {
+		return fk, pk, nil
+	}
+	fk, pk, err = lookup(right, left)
+	if err != nil {
+		return "", "", err
+	}
+	if fk != "" {
+		return pk, fk, nil
+	}
+	return "", "", newArgError("No foreign key found between %s and %s, please specify the join columns", left, right)
+}
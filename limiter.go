@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// errBusy is returned when a tool call can't start because too many are
+// already running.
+var errBusy = errors.New("Too many queries are running, try again later")
+
+// queryLimiter bounds the number of tool calls running SQL at once, so that
+// clients can't overwhelm the warehouse. A nil limiter doesn't limit.
+type queryLimiter struct {
+	slots chan struct{}
+	// timeout is how long to wait for a slot before giving up.
+	timeout time.Duration
+}
+
+// newQueryLimiter returns a limiter allowing max concurrent calls, or nil if
+// max is zero.
+func newQueryLimiter(max int, timeout time.Duration) *queryLimiter {
+	if max <= 0 {
+		return nil
+	}
+	return &queryLimiter{
+		slots:   make(chan struct{}, max),
+		timeout: timeout,
+	}
+}
+
+// acquire waits for a free slot, returning a function to release it.
+func (l *queryLimiter) acquire(ctx context.Context) (func(), error) {
+	if l == nil {
+		return func() {}, nil
+	}
+	timer := time.NewTimer(l.timeout)
+	defer timer.Stop()
+	select {
+	case l.slots <- struct{}{}:
+		return func() { <-l.slots }, nil
+	case <-timer.C:
+		return nil, errBusy
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
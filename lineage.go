@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"sort"
+)
+
+// ddlReferencePat matches object names following FROM or JOIN in a view
+// definition.
+var ddlReferencePat = regexp.MustCompile(`(?i)\b(?:FROM|JOIN)\s+((?:"(?:[^"]|"")+"|[A-Za-z_][A-Za-z0-9_$]*)(?:\s*\.\s*(?:"(?:[^"]|"")+"|[A-Za-z_][A-Za-z0-9_$]*)){0,2})`)
+
+// viewDependencies returns the objects a view directly depends on. It uses
+// SNOWFLAKE.ACCOUNT_USAGE.OBJECT_DEPENDENCIES, falling back to the names
+// following FROM and JOIN in the view definition when the role can't access
+// account usage.
+func viewDependencies(ctx context.Context, runner *queryRunner, dbName, schemaName, viewName string) (map[string]any, error) {
+	result, err := runner.runQuery(ctx,
+		`SELECT REFERENCED_DATABASE, REFERENCED_SCHEMA, REFERENCED_OBJECT_NAME, REFERENCED_OBJECT_DOMAIN, DEPENDENCY_TYPE
+FROM SNOWFLAKE.ACCOUNT_USAGE.OBJECT_DEPENDENCIES
+WHERE REFERENCING_DATABASE = ? AND REFERENCING_SCHEMA = ? AND REFERENCING_OBJECT_NAME = ? AND REFERENCING_OBJECT_DOMAIN IN ('VIEW', 'MATERIALIZED VIEW', 'SECURE VIEW')
+ORDER BY 1, 2, 3`,
+		dbName, schemaName, viewName,
+	)
+	if err == nil {
+		result["source"] = "SNOWFLAKE.ACCOUNT_USAGE.OBJECT_DEPENDENCIES, which may lag behind by up to 3 hours"
+		return result, nil
+	}
+	slog.Info("Falling back to the view definition for dependencies", "error", err)
+
+	ddl, ddlErr := getDDL(ctx, runner.db, "VIEW", quoteTableName(dbName, schemaName, viewName))
+	if ddlErr != nil {
+		return nil, fmt.Errorf("Failed to get view dependencies from ACCOUNT_USAGE (%v) or the view definition: %w", err, ddlErr)
+	}
+	seen := map[string]bool{}
+	names := []string{}
+	for _, m := range ddlReferencePat.FindAllStringSubmatch(ddl, -1) {
+		if !seen[m[1]] {
+			seen[m[1]] = true
+			names = append(names, m[1])
+		}
+	}
+	sort.Strings(names)
+	return map[string]any{
+		"referenced_names": names,
+		"source":           "Names following FROM and JOIN in the view definition, which may include CTEs and table functions and may not be fully qualified",
+	}, nil
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// maxListingLimit is the largest page size accepted by listing resources,
+// bounded by the maximum LIMIT of Snowflake SHOW commands.
+const maxListingLimit = 10000
+
+// listingPage is the page of a listing resource requested through the
+// offset and limit URI query parameters.
+type listingPage struct {
+	offset int
+	// limit is the page size. Zero means the listing is not paginated.
+	limit int
+}
+
+// addListingTemplate registers a listing resource template along with a
+// variant accepting ?offset=&limit= query parameters for pagination, each also
+// with a trailing slash.
+func addListingTemplate(s *server.MCPServer, uriTemplate, name, description string, handler server.ResourceTemplateHandlerFunc) {
+	addResourceTemplate(s, mcp.NewResourceTemplate(
+		uriTemplate,
+		name,
+		mcp.WithTemplateDescription(description),
+		mcp.WithTemplateMIMEType("text/plain"),
+	), handler)
+	for _, base := range []string{uriTemplate, uriTemplate + "/"} {
+		s.AddResourceTemplate(mcp.NewResourceTemplate(
+			base+"{?offset,limit}",
+			name+" (paginated)",
+			mcp.WithTemplateDescription(fmt.Sprintf("%s, paginated. Limit must be between 1 and %d. A next page URI is included as the last entry when there are more results", description, maxListingLimit)),
+			mcp.WithTemplateMIMEType("text/plain"),
+		), handler)
+	}
+}
+
+// parseListingURI splits a listing resource URI into the URI without the
+// query string and the requested page.
+func parseListingURI(uri string) (string, listingPage, error) {
+	page := listingPage{}
+	base, rawQuery, found := strings.Cut(uri, "?")
+	base = strings.TrimSuffix(base, "/")
+	if !found {
+		return base, page, nil
+	}
+	q, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return "", page, fmt.Errorf("Invalid URI query: %v", err)
+	}
+	if v := q.Get("offset"); v != "" {
+		if page.offset, err = strconv.Atoi(v); err != nil || page.offset < 0 {
+			return "", page, fmt.Errorf("Offset must be a non-negative integer")
+		}
+	}
+	if v := q.Get("limit"); v != "" {
+		if page.limit, err = strconv.Atoi(v); err != nil || page.limit < 1 {
+			return "", page, fmt.Errorf("Limit must be a positive integer")
+		}
+	} else if page.offset > 0 {
+		return "", page, fmt.Errorf("Offset requires limit")
+	}
+	if page.offset+page.limit >= maxListingLimit {
+		return "", page, fmt.Errorf("Offset plus limit must be less than %d", maxListingLimit)
+	}
+	return base, page, nil
+}
+
+// getNamePage is like getNameList but only returns the requested page of
+// names from a SHOW query, followed by an entry pointing at the next page
+// when there are more names.
+func getNamePage(ctx context.Context, db *sqlx.DB, query, uri string, page listingPage, conv func(name string) mcp.ResourceContents) ([]mcp.ResourceContents, error) {
+	return getObjectPage(ctx, db, query, uri, page, func(o listedObject) mcp.ResourceContents { return conv(o.Name) })
+}
+
+// showWithoutLimit are SHOW commands that don't accept LIMIT. Pages of their
+// rows are cut out after fetching them all.
+var showWithoutLimit = []string{"SHOW STAGES", "SHOW SEQUENCES", "SHOW PIPES", "SHOW MATERIALIZED VIEWS"}
+
+// getObjectPage is like getNamePage but passes the kind of each object too.
+func getObjectPage(ctx context.Context, db *sqlx.DB, query, uri string, page listingPage, conv func(o listedObject) mcp.ResourceContents) ([]mcp.ResourceContents, error) {
+	if page.limit == 0 {
+		return getObjectList(ctx, db, query, conv)
+	}
+
+	// Fetch one extra row to find out whether there is a next page.
+	end := page.offset + page.limit
+	limited := fmt.Sprintf("%s LIMIT %d", query, end+1)
+	for _, show := range showWithoutLimit {
+		if strings.HasPrefix(query, show+" ") {
+			limited = query
+		}
+	}
+	ret, err := getObjectList(ctx, db, limited, conv)
+	if err != nil {
+		return nil, err
+	}
+	more := len(ret) > end
+	ret = ret[min(page.offset, len(ret)):min(end, len(ret))]
+	if more {
+		next := fmt.Sprintf("%s?offset=%d&limit=%d", uri, end, page.limit)
+		ret = append(ret, mcp.TextResourceContents{
+			URI:      next,
+			MIMEType: "text/plain",
+			Text:     "More results available at " + next,
+		})
+	}
+	return ret, nil
+}
+
+// addSchemaListing registers a paginated resource at
+// snowflake://{database-name}/{schema-name}/<listPath> listing the objects
+// returned by the show command run IN SCHEMA, each linking to
+// snowflake://{database-name}/{schema-name}/<itemPath>/<name>.
+func addSchemaListing(s *server.MCPServer, mw resourceMiddleware, db *sqlx.DB, listPath, itemPath, show, name, description string) {
+	pat := regexp.MustCompile(`^snowflake://([^/]+)/([^/]+)/` + regexp.QuoteMeta(listPath) + `$`)
+	addListingTemplate(s,
+		"snowflake://{database-name}/{schema-name}/"+listPath,
+		name,
+		description,
+		mw.wrap(func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+			uri, page, err := parseListingURI(request.Params.URI)
+			if err != nil {
+				return nil, err
+			}
+			m, err := matchURI(pat, uri)
+			if err != nil {
+				return nil, err
+			}
+			if m == nil {
+				return nil, fmt.Errorf("Invalid URI")
+			}
+			dbName, schemaName := m[1], m[2]
+			return getNamePage(ctx, db, fmt.Sprintf(`%s IN SCHEMA %s.%s`, show, sqlIdent(dbName), sqlIdent(schemaName)), uri, page, func(name string) mcp.ResourceContents {
+				return mcp.TextResourceContents{
+					URI:      resourceURI(dbName, schemaName, itemPath, name),
+					MIMEType: "text/plain",
+					Text:     name,
+				}
+			})
+		}),
+	)
+}
+
+// objectKindPaths maps the kinds reported by SHOW OBJECTS to the resource
+// paths of the objects.
+var objectKindPaths = map[string]string{
+	"TABLE":             "table",
+	"VIEW":              "view",
+	"MATERIALIZED VIEW": "materialized-view",
+	"MATERIALIZED_VIEW": "materialized-view",
+	"EXTERNAL TABLE":    "external-table",
+	"EXTERNAL_TABLE":    "external-table",
+}
+
+// addObjectListing registers a paginated resource at
+// snowflake://{database-name}/{schema-name}/objects listing both tables and
+// views, each linking to its own resource according to its kind. Other kinds
+// of tables, such as dynamic tables, link to table resources.
+func addObjectListing(s *server.MCPServer, mw resourceMiddleware, db *sqlx.DB) {
+	pat := regexp.MustCompile(`^snowflake://([^/]+)/([^/]+)/objects$`)
+	addListingTemplate(s,
+		"snowflake://{database-name}/{schema-name}/objects",
+		"Table and view list in schema",
+		"List of tables and views of all kinds in a schema, each linking to its table, view, materialized-view or external-table resource",
+		mw.wrap(func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+			uri, page, err := parseListingURI(request.Params.URI)
+			if err != nil {
+				return nil, err
+			}
+			m, err := matchURI(pat, uri)
+			if err != nil {
+				return nil, err
+			}
+			if m == nil {
+				return nil, fmt.Errorf("Invalid URI")
+			}
+			dbName, schemaName := m[1], m[2]
+			return getObjectPage(ctx, db, fmt.Sprintf(`SHOW TERSE OBJECTS IN SCHEMA %s.%s`, sqlIdent(dbName), sqlIdent(schemaName)), uri, page, func(o listedObject) mcp.ResourceContents {
+				itemPath, ok := objectKindPaths[strings.ToUpper(o.Kind)]
+				if !ok {
+					itemPath = "table"
+				}
+				return mcp.TextResourceContents{
+					URI:      resourceURI(dbName, schemaName, itemPath, o.Name),
+					MIMEType: "text/plain",
+					Text:     o.Name,
+				}
+			})
+		}),
+	)
+}
@@ -1,288 +1,264 @@
 package main
 
 import (
-	"bytes"
 	"context"
 	"database/sql"
-	"encoding/json"
-	"flag"
+	"errors"
 	"fmt"
-	"log"
-	"regexp"
+	"log/slog"
+	"os"
+	"runtime/debug"
+	"strings"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 
-	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 	"github.com/snowflakedb/gosnowflake"
 )
 
-func run() error {
-	var (
-		snowflakeAccount   = flag.String("account", "", "Snowflake account name")
-		snowflakeRole      = flag.String("role", "", "Snowflake role name")
-		snowflakeWarehouse = flag.String("warehouse", "", "Snowflake warehouse name")
-	)
-	flag.Parse()
-	if *snowflakeAccount == "" || *snowflakeRole == "" {
-		return fmt.Errorf("Please provide account and role")
-	}
-
-	// Setup connection to snowflake using browser auth
+// version is the snowflake-mcp build version, set at build time with
+// -ldflags "-X main.version=...". Otherwise the module version is used when
+// installed with go install.
+var version = "dev"
 
-	sfconfig := gosnowflake.Config{
-		Account:       *snowflakeAccount,
-		Role:          *snowflakeRole,
-		Warehouse:     *snowflakeWarehouse,
-		Authenticator: gosnowflake.AuthTypeExternalBrowser,
+func init() {
+	if version != "dev" {
+		return
 	}
-	connector := gosnowflake.NewConnector(gosnowflake.SnowflakeDriver{}, sfconfig)
-	db := sqlx.NewDb(sql.OpenDB(connector), "snowflake").Unsafe()
-
-	// Create MCP server
-
-	mcpServer := server.NewMCPServer(
-		"Snowflake",
-		"1.0.0",
-		server.WithResourceCapabilities(false, false),
-	)
-
-	mcpServer.AddResource(mcp.NewResource(
-		"snowflake://",
-		"Database list",
-		mcp.WithResourceDescription("List of databases"),
-		mcp.WithMIMEType("text/plain"),
-	), func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
-		return getNameList(db, "SHOW TERSE DATABASES", func(name string) mcp.ResourceContents {
-			return mcp.TextResourceContents{
-				URI:      fmt.Sprintf("snowflake://%s", name),
-				MIMEType: "text/plain",
-				Text:     name,
-			}
-		})
-	})
-
-	schemaPat := regexp.MustCompile(`^snowflake://([^/]+)$`)
-	mcpServer.AddResourceTemplate(mcp.NewResourceTemplate(
-		"snowflake://{database-name}",
-		"Schema list in database",
-		mcp.WithTemplateDescription("List of schemas in a database"),
-		mcp.WithTemplateMIMEType("text/plain"),
-	), func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
-		m := schemaPat.FindStringSubmatch(request.Params.URI)
-		if m == nil {
-			return nil, fmt.Errorf("Invalid URI")
-		}
-		dbName := m[1]
-		return getNameList(db, fmt.Sprintf(`SHOW TERSE SCHEMAS IN DATABASE %s`, dbName), func(name string) mcp.ResourceContents {
-			return mcp.TextResourceContents{
-				URI:      fmt.Sprintf("snowflake://%s/%s", dbName, name),
-				MIMEType: "text/plain",
-				Text:     name,
-			}
-		})
-	})
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		version = info.Main.Version
+	}
+}
 
-	tablesPat := regexp.MustCompile(`^snowflake://([^/]+)/([^/]+)/tables$`)
-	mcpServer.AddResourceTemplate(mcp.NewResourceTemplate(
-		"snowflake://{database-name}/{schema-name}/tables",
-		"Table list in schema",
-		mcp.WithTemplateDescription("List of tables in a schema"),
-		mcp.WithTemplateMIMEType("text/plain"),
-	), func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
-		m := tablesPat.FindStringSubmatch(request.Params.URI)
-		if m == nil {
-			return nil, fmt.Errorf("Invalid URI")
-		}
-		dbName := m[1]
-		schemaName := m[2]
+func run() error {
+	c := parseFlags()
+	if err := setupLogging(c.logLevel, c.logFormat); err != nil {
+		return err
+	}
+	if err := c.load(); err != nil {
+		return err
+	}
+	quoteIdentifiers = c.quoteIdents
+	allowed, err := newDatabaseAllowlist(c.allowedDatabases)
+	if err != nil {
+		return fmt.Errorf("Invalid allowed database: %w", err)
+	}
+	resultOpts := resultOptions{
+		floatPrecision: c.floatPrecision,
+		maxCellBytes:   c.maxCellBytes,
+		maxArrowBytes:  c.maxArrowBytes,
 
-		return getNameList(db, fmt.Sprintf(`SHOW TERSE TABLES IN SCHEMA %s.%s`, dbName, schemaName), func(name string) mcp.ResourceContents {
-			return mcp.TextResourceContents{
-				URI:      fmt.Sprintf("snowflake://%s/%s/table/%s", dbName, schemaName, name),
-				MIMEType: "text/plain",
-				Text:     name,
-			}
-		})
-	})
+		maxResponseBytes: c.maxResponseBytes,
+	}
 
-	viewsPat := regexp.MustCompile(`^snowflake://([^/]+)/([^/]+)/views$`)
-	mcpServer.AddResourceTemplate(mcp.NewResourceTemplate(
-		"snowflake://{database-name}/{schema-name}/views",
-		"View list in schema",
-		mcp.WithTemplateDescription("List of views in a schema"),
-		mcp.WithTemplateMIMEType("text/plain"),
-	), func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
-		m := viewsPat.FindStringSubmatch(request.Params.URI)
-		if m == nil {
-			return nil, fmt.Errorf("Invalid URI")
-		}
-		dbName, schemaName := m[1], m[2]
-		return getNameList(db, fmt.Sprintf(`SHOW TERSE TABLES IN SCHEMA %s.%s`, dbName, schemaName), func(name string) mcp.ResourceContents {
-			return mcp.TextResourceContents{
-				URI:      fmt.Sprintf("snowflake://%s/%s/view/%s", dbName, schemaName, name),
-				MIMEType: "text/plain",
-				Text:     name,
-			}
-		})
-	})
+	db, sessions, err := c.connect()
+	if err != nil {
+		return err
+	}
 
-	defPat := regexp.MustCompile(`^snowflake://([^/]+)/([^/]+)/(?:view|table)/([^/]+)$`)
-	vtDefHandler := func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
-		m := defPat.FindStringSubmatch(request.Params.URI)
-		if m == nil {
-			return nil, fmt.Errorf("Invalid URI")
-		}
-		dbName, schemaName, tableName := m[1], m[2], m[3]
-		rows, err := db.Queryx(fmt.Sprintf("DESCRIBE TABLE %s.%s.%s", dbName, schemaName, tableName))
+	retry := retryPolicy{
+		retries: c.queryRetries,
+		delay:   c.queryRetryDelay,
+	}
+	runner := &queryRunner{
+		db:          db,
+		opts:        resultOpts,
+		autoLimit:   c.autoLimit,
+		retry:       retry,
+		reportTotal: c.reportTotal,
+	}
+	switch c.queryLogPath {
+	case "":
+	case "-":
+		runner.log = newQueryLog(os.Stderr)
+	default:
+		f, err := os.OpenFile(c.queryLogPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
 		if err != nil {
-			return nil, fmt.Errorf("Failed to get table def for %s.%s.%s: %v", dbName, schemaName, tableName, err)
+			return fmt.Errorf("Failed to open query log: %w", err)
 		}
-		defer rows.Close()
+		defer f.Close()
+		runner.log = newQueryLog(f)
+	}
+	defer runner.log.close()
 
-		type column struct {
-			Name string `db:"name" json:"name"`
-			Type string `db:"type" json:"type"`
-			Kind string `db:"kind" json:"-"`
+	var resultsCache *resultCache
+	if c.resultCacheDir != "" {
+		if resultsCache, err = newResultCache(c.resultCacheDir, c.resultCacheSize); err != nil {
+			return err
 		}
+		defer resultsCache.close()
+	}
+	cursors := newResultCursors(c.maxResultPages)
 
-		columns := []column{}
-		for rows.Next() {
-			t := column{}
-			if err = rows.StructScan(&t); err != nil {
-				return nil, fmt.Errorf("Failed to scan rows: %v", err)
-			}
-			if t.Kind != "COLUMN" {
-				continue
-			}
-			columns = append(columns, column(t))
-		}
+	mw := resourceMiddleware{
+		allowed: allowed,
+		retry:   retry,
+	}
+	if !c.noCache {
+		mw.cache = newResourceCache(c.cacheTTL)
+	}
 
-		b := bytes.NewBuffer(nil)
-		enc := json.NewEncoder(b)
-		enc.SetIndent("", " ")
-		if err := enc.Encode(map[string]any{
-			"columns": columns,
-		}); err != nil {
-			return nil, fmt.Errorf("Failed to marshal result: %v", err)
-		}
+	// Create MCP server
 
-		return []mcp.ResourceContents{
-			mcp.TextResourceContents{
-				URI:      request.Params.URI,
-				MIMEType: "application/json",
-				Text:     b.String(),
-			},
-		}, nil
+	opts := []server.ServerOption{}
+	if !c.disableResources {
+		opts = append(opts, server.WithResourceCapabilities(false, false))
+	}
+	mcpServer := server.NewMCPServer(c.mcpServerName, c.mcpServerVersion, opts...)
+	if !c.disableResources {
+		addResources(mcpServer, mw, db, c.hideSystemDBs)
+	}
+	if c.readOnly {
+		c.disabledTools = append(c.disabledTools, "execute", "put_file", "abort_session")
+	}
+	if c.transferDir == "" {
+		c.disabledTools = append(c.disabledTools, "put_file", "get_file")
 	}
+	tools := newToolRegistry(mcpServer, c.disabledTools, &warehouseGuard{
+		db:         db,
+		name:       c.snowflakeWarehouse,
+		autoResume: c.autoResume,
+	}, newQueryLimiter(c.maxConcurrent, c.queueTimeout), newProgressNotifier(mcpServer, c.progressInterval))
 
-	mcpServer.AddResourceTemplate(mcp.NewResourceTemplate(
-		"snowflake://{database-name}/{schema-name}/table/{table-name}",
-		"Table definition",
-		mcp.WithTemplateDescription("Definition of a table including columns and column types"),
-		mcp.WithTemplateMIMEType("application/json"),
-	), vtDefHandler)
+	registerQueryTools(tools, db, runner, allowed, queryToolConfig{
+		defaultFormat:    c.defaultFormat,
+		nullString:       c.nullString,
+		truncateMode:     c.truncateMode,
+		sessionIsolation: c.sessionIsolation,
+		readOnly:         c.readOnly,
+		costWarnings:     c.costWarnings,
+		structuredOutput: c.structuredOutput,
+		resultCache:      resultsCache,
+		resultCursors:    cursors,
+	})
+	registerTransferTools(tools, runner, allowed, c.transferDir)
+	registerSearchTools(tools, db, runner, allowed)
+	registerSchemaTools(tools, db, runner, allowed, c.maxResponseBytes)
+	registerDataTools(tools, db, runner, allowed)
+	registerSessionTools(tools, db, runner, sessions, cursors)
 
-	mcpServer.AddResourceTemplate(mcp.NewResourceTemplate(
-		"snowflake://{database-name}/{schema-name}/view/{table-name}",
-		"View definition",
-		mcp.WithTemplateDescription("Definition of a view including columns and column types"),
-		mcp.WithTemplateMIMEType("application/json"),
-	), vtDefHandler)
+	if err := tools.checkDisabled(); err != nil {
+		return err
+	}
 
-	// Add a query tool.
-	mcpServer.AddTool(mcp.NewTool(
-		"query",
-		mcp.WithDescription("Execute a SQL query."),
-		mcp.WithString("query",
-			mcp.Required(),
-			mcp.Description("SQL query to execute.  You must use full database.schema.table when referencing tables."),
-		),
-	), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return server.ServeStdio(mcpServer)
+}
 
-		query, _ := request.Params.Arguments["query"].(string)
-		const maxResultRows = 1000
+// connect opens the connection pool to Snowflake and validates it, along with
+// the session parameters given with -session-param.
+func (c *config) connect() (*sqlx.DB, *sessionTracker, error) {
+	sfconfig, sessionParams, err := c.snowflakeConfig()
+	if err != nil {
+		return nil, nil, err
+	}
+	sessions := newSessionTracker(gosnowflake.NewConnector(gosnowflake.SnowflakeDriver{}, sfconfig))
+	db := sqlx.NewDb(sql.OpenDB(sessions), "snowflake").Unsafe()
+	db.SetMaxOpenConns(c.maxOpenConns)
+	db.SetMaxIdleConns(c.maxIdleConns)
+	db.SetConnMaxLifetime(c.connMaxLifetime)
+	slog.Info("Connecting to Snowflake", "account", c.snowflakeAccount, "role", c.snowflakeRole, "warehouse", c.snowflakeWarehouse, "auth", sfconfig.Authenticator.String())
+	if err := pingWithRetry(db, c.connectRetries, c.connectTimeout); err != nil {
+		return nil, nil, err
+	}
+	slog.Info("Connected to Snowflake")
+	if err := checkSessionParams(context.Background(), db, sessionParams); err != nil {
+		return nil, nil, err
+	}
+	if c.selfTestOnStart {
+		logSelfTest(selfTest(context.Background(), db))
+	}
+	return db, sessions, nil
+}
 
-		// Execute the query.
-		rows, err := db.QueryxContext(ctx, query)
-		if err != nil {
-			return nil, fmt.Errorf("Failed to execute query: %v", err)
+// pingWithRetry validates the connection to Snowflake, retrying network
+// failures with exponential backoff. Authentication failures are not retried.
+func pingWithRetry(db *sqlx.DB, retries int, timeout time.Duration) error {
+	backoff := time.Second
+	for attempt := 0; ; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		err := db.PingContext(ctx)
+		cancel()
+		if err == nil {
+			return nil
 		}
-		defer rows.Close()
-
-		// Get column details.
-		columnInfo := []map[string]any{}
-		columnTypes, err := rows.ColumnTypes()
-		if err != nil {
-			return nil, fmt.Errorf("Failed to get column types: %v", err)
+		if isAuthError(err) {
+			return fmt.Errorf("Failed to authenticate with Snowflake: %w", err)
 		}
-		for _, columnType := range columnTypes {
-			columnInfo = append(columnInfo, map[string]any{
-				"name": columnType.Name(),
-				"type": columnType.DatabaseTypeName(),
-			})
+		if errors.Is(err, context.DeadlineExceeded) {
+			err = fmt.Errorf("Timed out after %v, see -connect-timeout and -login-timeout: %w", timeout, err)
 		}
-
-		// Fetch the rows.
-		rowsSlice := [][]any{}
-		for rows.Next() {
-			r := []any{}
-			r, err := rows.SliceScan()
-			if err != nil {
-				return nil, fmt.Errorf("Failed to scan row: %v", err)
-			}
-			rowsSlice = append(rowsSlice, r)
-			if len(rowsSlice) >= maxResultRows {
-				break
-			}
+		if attempt >= retries {
+			return fmt.Errorf("Failed to connect to Snowflake after %d attempts: %w", attempt+1, err)
 		}
+		slog.Warn("Failed to connect to Snowflake, retrying", "attempt", attempt+1, "backoff", backoff, "error", err)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
 
-		result := map[string]any{
-			"column_info": columnInfo,
-			"rows":        rowsSlice,
-			"notice":      fmt.Sprintf("Only first %d rows are shown", maxResultRows),
-		}
-		b := bytes.NewBuffer(nil)
-		jsonEnc := json.NewEncoder(b)
-		jsonEnc.SetIndent("", " ")
-		if err := jsonEnc.Encode(result); err != nil {
-			return nil, fmt.Errorf("Failed to marshal result: %v", err)
-		}
+// checkHost returns an error if host is set but isn't a host of account, as
+// a guard against connecting to the wrong account. The first label of the
+// host must start with the account identifier, e.g. myorg-myaccount for
+// myorg-myaccount.privatelink.snowflakecomputing.com.
+func checkHost(account, host string) error {
+	if host == "" {
+		return nil
+	}
+	label, _, _ := strings.Cut(strings.ToLower(host), ".")
+	// Legacy account locators may include the region, which is a separate
+	// label of the host. Underscores in account identifiers are replaced by
+	// hyphens in host names.
+	acct, _, _ := strings.Cut(strings.ToLower(account), ".")
+	if !strings.HasPrefix(label, acct) && !strings.HasPrefix(label, strings.ReplaceAll(acct, "_", "-")) {
+		return fmt.Errorf("Host %s doesn't match account %s", host, account)
+	}
+	return nil
+}
 
-		return &mcp.CallToolResult{
-			Content: []mcp.Content{
-				mcp.TextContent{
-					Type: "text",
-					Text: b.String(),
-				},
-			},
-		}, nil
-	})
-	return server.ServeStdio(mcpServer)
+// isAuthError reports whether err is a Snowflake authentication or session
+// error, as opposed to a network or query failure.
+func isAuthError(err error) bool {
+	var sfErr *gosnowflake.SnowflakeError
+	if !errors.As(err, &sfErr) {
+		return false
+	}
+	return sfErr.Number >= 390000 && sfErr.Number < 400000
+}
+
+func getNameList[T any](ctx context.Context, db *sqlx.DB, query string, conv func(name string) T) ([]T, error) {
+	return getObjectList(ctx, db, query, func(o listedObject) T { return conv(o.Name) })
+}
+
+// listedObject is an object returned by a SHOW command. Kind is only set by
+// commands returning objects of several kinds, such as SHOW OBJECTS.
+type listedObject struct {
+	Name string `db:"name"`
+	Kind string `db:"kind"`
 }
 
-func getNameList[T any](db *sqlx.DB, query string, conv func(name string) T) ([]T, error) {
-	rows, err := db.Queryx(query)
+// getObjectList is like getNameList but passes the kind of each object too.
+func getObjectList[T any](ctx context.Context, db *sqlx.DB, query string, conv func(o listedObject) T) ([]T, error) {
+	rows, err := db.QueryxContext(ctx, query)
 	if err != nil {
-		return nil, fmt.Errorf("Failed to run query '%s': %v", query, err)
+		return nil, fmt.Errorf("Failed to run query '%s': %w", query, err)
 	}
 	defer rows.Close()
 
 	ret := []T{}
 	for rows.Next() {
-		t := struct {
-			Name string `db:"name"`
-		}{}
-		if err = rows.StructScan(&t); err != nil {
+		o := listedObject{}
+		if err = rows.StructScan(&o); err != nil {
 			return nil, fmt.Errorf("Failed to scan rows: %v", err)
 		}
-		ret = append(ret, conv(t.Name))
+		ret = append(ret, conv(o))
 	}
 	return ret, nil
 }
 
 func main() {
 	if err := run(); err != nil {
-		log.Fatalf("Error: %v", err)
+		slog.Error("Exiting", "error", err)
+		os.Exit(1)
 	}
 }
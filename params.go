@@ -0,0 +1,199 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// withProperty adds a tool input property with an arbitrary JSON schema, for
+// property types not covered by mcp-go.
+func withProperty(name string, schema map[string]any) mcp.ToolOption {
+	return func(t *mcp.Tool) {
+		t.InputSchema.Properties[name] = schema
+	}
+}
+
+// bindParams converts query parameters given as a JSON array (for positional
+// ? or :1 placeholders) or a JSON object (for :name placeholders) to
+// arguments for the driver.
+func bindParams(params any) ([]any, error) {
+	switch params := params.(type) {
+	case nil:
+		return nil, nil
+	case []any:
+		args := make([]any, len(params))
+		for i, p := range params {
+			v, err := bindValue(p)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid parameter %d: %w", i+1, err)
+			}
+			args[i] = v
+		}
+		return args, nil
+	case map[string]any:
+		names := make([]string, 0, len(params))
+		for name := range params {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		args := make([]any, len(names))
+		for i, name := range names {
+			v, err := bindValue(params[name])
+			if err != nil {
+				return nil, fmt.Errorf("Invalid parameter %q: %w", name, err)
+			}
+			args[i] = sql.Named(name, v)
+		}
+		return args, nil
+	default:
+		return nil, newArgError("Parameters must be a JSON array or object")
+	}
+}
+
+// bindValue converts a JSON value to a driver value. Whole numbers are bound
+// as integers so they compare exactly against NUMBER columns.
+func bindValue(v any) (any, error) {
+	switch v := v.(type) {
+	case nil, string, bool:
+		return v, nil
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+			return int64(v), nil
+		}
+		return v, nil
+	default:
+		return nil, newArgError("Only strings, numbers, booleans and null are supported")
+	}
+}
+
+// argError is an invalid tool argument. It is reported to the client as a
+// tool error result rather than a protocol error.
+type argError struct {
+	msg string
+}
+
+func (e *argError) Error() string {
+	return e.msg
+}
+
+func newArgError(format string, a ...any) error {
+	return &argError{msg: fmt.Sprintf(format, a...)}
+}
+
+// stringArg returns the tool argument name as a string. Missing optional
+// arguments are returned as empty strings.
+func stringArg(args map[string]any, name string, required bool) (string, error) {
+	v, ok := args[name]
+	if !ok || v == nil {
+		if required {
+			return "", newArgError("Missing required argument %s", name)
+		}
+		return "", nil
+	}
+	s, ok := v.(string)
+	if !ok {
+		return "", newArgError("Argument %s must be a string", name)
+	}
+	if required && s == "" {
+		return "", newArgError("Argument %s must not be empty", name)
+	}
+	return s, nil
+}
+
+// nullStringArg returns the null_string tool argument, or def if it's
+// missing. Unlike other string arguments, an empty string is a valid override.
+func nullStringArg(args map[string]any, def string) (string, error) {
+	if v, ok := args["null_string"]; !ok || v == nil {
+		return def, nil
+	}
+	return stringArg(args, "null_string", false)
+}
+
+// enumArg returns the tool argument name, which must be one of values, or def
+// if it's missing.
+func enumArg(args map[string]any, name, def string, values ...string) (string, error) {
+	v, err := stringArg(args, name, false)
+	if err != nil {
+		return "", err
+	}
+	if v == "" {
+		return def, nil
+	}
+	if !slices.Contains(values, v) {
+		return "", newArgError("Argument %s must be one of %s", name, strings.Join(values, ", "))
+	}
+	return v, nil
+}
+
+// boolArg returns the tool argument name as a boolean, or def if it's
+// missing.
+func boolArg(args map[string]any, name string, def bool) (bool, error) {
+	v, ok := args[name]
+	if !ok || v == nil {
+		return def, nil
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, newArgError("Argument %s must be a boolean", name)
+	}
+	return b, nil
+}
+
+// numberArg returns the tool argument name as a number, or def if it's
+// missing.
+func numberArg(args map[string]any, name string, def float64) (float64, error) {
+	v, ok := args[name]
+	if !ok || v == nil {
+		return def, nil
+	}
+	n, ok := v.(float64)
+	if !ok {
+		return 0, newArgError("Argument %s must be a number", name)
+	}
+	return n, nil
+}
+
+// maxIntArg bounds integer arguments to the integers a float64, as JSON
+// numbers are decoded, holds exactly.
+const maxIntArg = 1 << 53
+
+// intArg returns the tool argument name as an integer, or def if it's
+// missing.
+func intArg(args map[string]any, name string, def int) (int, error) {
+	n, err := numberArg(args, name, float64(def))
+	if err != nil {
+		return 0, err
+	}
+	if n != math.Trunc(n) {
+		return 0, newArgError("Argument %s must be an integer", name)
+	}
+	if n < -maxIntArg || n > maxIntArg {
+		return 0, newArgError("Argument %s is out of range", name)
+	}
+	return int(n), nil
+}
+
+// stringSliceArg returns the tool argument name as a slice of strings.
+func stringSliceArg(args map[string]any, name string) ([]string, error) {
+	v, ok := args[name]
+	if !ok || v == nil {
+		return nil, nil
+	}
+	items, ok := v.([]any)
+	if !ok {
+		return nil, newArgError("Argument %s must be an array of strings", name)
+	}
+	ret := make([]string, len(items))
+	for i, item := range items {
+		if ret[i], ok = item.(string); !ok {
+			return nil, newArgError("Argument %s must be an array of strings", name)
+		}
+	}
+	return ret, nil
+}
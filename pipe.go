@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// pipeStatus returns the status of a pipe as reported by
+// SYSTEM$PIPE_STATUS. name is the quoted, qualified name of the pipe.
+func pipeStatus(ctx context.Context, db *sqlx.DB, name string) (any, error) {
+	var raw string
+	if err := db.GetContext(ctx, &raw, "SELECT SYSTEM$PIPE_STATUS(?)", name); err != nil {
+		return nil, fmt.Errorf("Failed to get status of pipe %s: %w", name, err)
+	}
+	var status any
+	if err := json.Unmarshal([]byte(raw), &status); err != nil {
+		return raw, nil
+	}
+	return status, nil
+}
+
+// addPipeResources registers the pipe listing and definition resources.
+func addPipeResources(s *server.MCPServer, mw resourceMiddleware, db *sqlx.DB) {
+	addSchemaListing(s, mw, db, "pipes", "pipe", "SHOW PIPES", "Pipe list in schema", "List of Snowpipe pipes in a schema")
+
+	pat := regexp.MustCompile(`^snowflake://([^/]+)/([^/]+)/pipe/([^/]+)$`)
+	addResourceTemplate(s, mcp.NewResourceTemplate(
+		"snowflake://{database-name}/{schema-name}/pipe/{name}",
+		"Pipe definition",
+		mcp.WithTemplateDescription("Definition of a pipe including its COPY statement, along with its execution state and pending files when the role may monitor it"),
+		mcp.WithTemplateMIMEType("application/json"),
+	), mw.wrap(func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		m, err := matchURI(pat, request.Params.URI)
+		if err != nil {
+			return nil, err
+		}
+		if m == nil {
+			return nil, fmt.Errorf("Invalid URI")
+		}
+		name := fmt.Sprintf("%s.%s.%s", sqlIdent(m[1]), sqlIdent(m[2]), sqlIdent(m[3]))
+		props, err := describeObject(ctx, db, "DESCRIBE PIPE", name)
+		if err != nil {
+			return nil, err
+		}
+		// The status requires the MONITOR or OPERATE privilege, which
+		// shouldn't stop the definition from being returned.
+		if props["pipe_status"], err = pipeStatus(ctx, db, name); err != nil {
+			delete(props, "pipe_status")
+			props["pipe_status_error"] = err.Error()
+		}
+		return jsonResourceContents(request.Params.URI, props)
+	}))
+}
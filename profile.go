@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+)
+
+// profileColumn summarizes the distribution of the values of a column:
+// row, NULL and distinct counts along with the minimum and maximum values.
+func profileColumn(ctx context.Context, runner *queryRunner, dbName, schemaName, tableName, column string) (map[string]any, error) {
+	table := quoteTableName(dbName, schemaName, tableName)
+	col := quoteIdent(column)
+	res, err := runner.fetch(ctx, true, fmt.Sprintf(
+		"SELECT COUNT(*), COUNT(%[1]s), COUNT(DISTINCT %[1]s), MIN(%[1]s), MAX(%[1]s) FROM %[2]s", col, table,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("Failed to profile column %s of %s: %w", column, table, err)
+	}
+	if len(res.rows) != 1 {
+		return nil, fmt.Errorf("Failed to profile column %s of %s: no result", column, table)
+	}
+	row := res.rows[0]
+	counts := make([]int64, 3)
+	for i := range counts {
+		if counts[i], err = toInt64(row[i]); err != nil {
+			return nil, err
+		}
+	}
+	rowCount, nonNull, distinct := counts[0], counts[1], counts[2]
+
+	result := map[string]any{
+		"table":          table,
+		"column":         column,
+		"type":           res.columnTypes[3].DatabaseTypeName(),
+		"row_count":      rowCount,
+		"null_count":     rowCount - nonNull,
+		"distinct_count": distinct,
+		"min":            row[3],
+		"max":            row[4],
+	}
+	if rowCount > 0 {
+		result["null_percent"] = math.Round(float64(rowCount-nonNull)/float64(rowCount)*10000) / 100
+	}
+	if res.truncated {
+		result["notice"] = fmt.Sprintf("Values longer than %d bytes are truncated", runner.opts.maxCellBytes)
+	}
+	return result, nil
+}
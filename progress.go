@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// progressNotifier sends progress notifications while tool calls run, so that
+// clients show long queries as running rather than hung. A nil notifier
+// doesn't send any.
+type progressNotifier struct {
+	s *server.MCPServer
+	// interval is the time between notifications.
+	interval time.Duration
+}
+
+// newProgressNotifier returns a notifier sending a notification every
+// interval, or nil if interval is zero.
+func newProgressNotifier(s *server.MCPServer, interval time.Duration) *progressNotifier {
+	if interval <= 0 {
+		return nil
+	}
+	return &progressNotifier{s: s, interval: interval}
+}
+
+// start sends a notification every interval until the returned function is
+// called, if the client asked for progress with a progress token. Snowflake
+// doesn't report how far along a query is, so the progress is the number of
+// seconds elapsed.
+func (p *progressNotifier) start(tool string, request mcp.CallToolRequest) func() {
+	if p == nil || request.Params.Meta == nil || request.Params.Meta.ProgressToken == nil {
+		return func() {}
+	}
+	token := request.Params.Meta.ProgressToken
+	done := make(chan struct{})
+	go func() {
+		start := time.Now()
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				elapsed := time.Since(start).Round(time.Second)
+				err := p.s.SendNotificationToClient("notifications/progress", map[string]any{
+					"progressToken": token,
+					"progress":      elapsed.Seconds(),
+					"message":       fmt.Sprintf("%s still running, %s elapsed", tool, elapsed),
+				})
+				if err != nil {
+					slog.Warn("Failed to send progress notification", "tool", tool, "error", err)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() { close(done) }
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+)
+
+// configureProxy routes connections to Snowflake through an HTTP proxy. The
+// proxy is set through the standard HTTPS_PROXY and HTTP_PROXY environment
+// variables, which the driver honors for all its connections including OCSP
+// checks and stage transfers, and which can also be set directly instead.
+func configureProxy(host string, port int, user, password string) error {
+	if host == "" {
+		if user != "" || port != 0 {
+			return fmt.Errorf("Proxy options require a proxy host")
+		}
+		return nil
+	}
+	if port <= 0 || port > 65535 {
+		return fmt.Errorf("Proxy port must be between 1 and 65535")
+	}
+	u := &url.URL{
+		Scheme: "http",
+		Host:   net.JoinHostPort(host, strconv.Itoa(port)),
+	}
+	if user != "" {
+		u.User = url.UserPassword(user, password)
+	}
+	slog.Info("Connecting through proxy", "host", host, "port", port)
+	for _, k := range []string{"HTTPS_PROXY", "HTTP_PROXY"} {
+		if err := os.Setenv(k, u.String()); err != nil {
+			return fmt.Errorf("Failed to set %s: %v", k, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Data quality checks supported by the data_quality tool.
+const (
+	qualityCheckNulls      = "nulls"
+	qualityCheckDuplicates = "duplicates"
+	qualityCheckRange      = "range"
+)
+
+// qualityRange is the allowed range of values of a column. Either bound may
+// be omitted.
+type qualityRange struct {
+	Min any `json:"min,omitempty"`
+	Max any `json:"max,omitempty"`
+}
+
+// qualityIssue is a problem found by a data quality check.
+type qualityIssue struct {
+	Check   string `json:"check"`
+	Column  string `json:"column,omitempty"`
+	Message string `json:"message"`
+}
+
+// qualityRequest describes the data quality checks to run on a table.
+type qualityRequest struct {
+	table         string
+	checks        []string
+	nullThreshold float64
+	keyColumns    []string
+	ranges        map[string]qualityRange
+}
+
+// checkDataQuality runs the requested checks on a table using aggregate
+// queries and returns a report of the issues found.
+func checkDataQuality(ctx context.Context, db *sqlx.DB, req qualityRequest) (map[string]any, error) {
+	dbName, schemaName, tableName, err := parseTableName(req.table)
+	if err != nil {
+		return nil, err
+	}
+	table := quoteTableName(dbName, schemaName, tableName)
+	for _, check := range req.checks {
+		switch check {
+		case qualityCheckNulls, qualityCheckDuplicates, qualityCheckRange:
+		default:
+			return nil, newArgError("Unknown check %q", check)
+		}
+	}
+
+	var rowCount int64
+	if err := db.GetContext(ctx, &rowCount, "SELECT COUNT(*) FROM "+table); err != nil {
+		return nil, fmt.Errorf("Failed to count rows of %s: %w", table, err)
+	}
+
+	issues := []qualityIssue{}
+	for _, check := range req.checks {
+		var found []qualityIssue
+		switch check {
+		case qualityCheckNulls:
+			found, err = checkNulls(ctx, db, table, rowCount, req.nullThreshold)
+		case qualityCheckDuplicates:
+			found, err = checkDuplicateKeys(ctx, db, table, req.keyColumns)
+		case qualityCheckRange:
+			found, err = checkRanges(ctx, db, table, req.ranges)
+		}
+		if err != nil {
+			return nil, err
+		}
+		issues = append(issues, found...)
+	}
+
+	return map[string]any{
+		"table":     table,
+		"row_count": rowCount,
+		"checks":    req.checks,
+		"issues":    issues,
+	}, nil
+}
+
+// checkNulls reports columns whose fraction of NULL values exceeds threshold.
+func checkNulls(ctx context.Context, db *sqlx.DB, table string, rowCount int64, threshold float64) ([]qualityIssue, error) {
+	if rowCount == 0 {
+		return nil, nil
+	}
+	columns, err := describeTable(ctx, db, table)
+	if err != nil {
+		return nil, err
+	}
+	if len(columns) == 0 {
+		return nil, nil
+	}
+
+	counts := make([]string, len(columns))
+	for i, c := range columns {
+		counts[i] = fmt.Sprintf("COUNT(%s)", quoteIdent(c.Name))
+	}
+	values, err := db.QueryRowxContext(ctx, fmt.Sprintf("SELECT %s FROM %s", strings.Join(counts, ", "), table)).SliceScan()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to count nulls in %s: %w", table, err)
+	}
+
+	issues := []qualityIssue{}
+	for i, c := range columns {
+		nonNull, err := toInt64(values[i])
+		if err != nil {
+			return nil, err
+		}
+		rate := float64(rowCount-nonNull) / float64(rowCount)
+		if rate > threshold {
+			issues = append(issues, qualityIssue{
+				Check:   qualityCheckNulls,
+				Column:  c.Name,
+				Message: fmt.Sprintf("%.2f%% of values are NULL", rate*100),
+			})
+		}
+	}
+	return issues, nil
+}
+
+// checkDuplicateKeys reports duplicate values of the key columns, which
+// default to the primary key of the table.
+func checkDuplicateKeys(ctx context.Context, db *sqlx.DB, table string, keyColumns []string) ([]qualityIssue, error) {
+	if len(keyColumns) == 0 {
+		keys := []struct {
+			Column string `db:"column_name"`
+		}{}
+		if err := db.SelectContext(ctx, &keys, "SHOW PRIMARY KEYS IN TABLE "+table); err != nil {
+			return nil, fmt.Errorf("Failed to get primary key of %s: %w", table, err)
+		}
+		if len(keys) == 0 {
+			return []qualityIssue{{
+				Check:   qualityCheckDuplicates,
+				Message: "Table has no primary key and no key columns were given, skipped",
+			}}, nil
+		}
+		for _, k := range keys {
+			keyColumns = append(keyColumns, k.Column)
+		}
+	}
+
+	quoted := make([]string, len(keyColumns))
+	for i, c := range keyColumns {
+		quoted[i] = quoteIdent(c)
+	}
+	cols := strings.Join(quoted, ", ")
+
+	var duplicates int64
+	if err := db.GetContext(ctx, &duplicates, fmt.Sprintf(
+		"SELECT COUNT(*) FROM (SELECT %s FROM %s GROUP BY %s HAVING COUNT(*) > 1)", cols, table, cols,
+	)); err != nil {
+		return nil, fmt.Errorf("Failed to check duplicate keys in %s: %w", table, err)
+	}
+	if duplicates == 0 {
+		return nil, nil
+	}
+	return []qualityIssue{{
+		Check:   qualityCheckDuplicates,
+		Column:  strings.Join(keyColumns, ", "),
+		Message: fmt.Sprintf("%d key values appear more than once", duplicates),
+	}}, nil
+}
+
+// checkRanges reports columns with values outside their allowed range.
+func checkRanges(ctx context.Context, db *sqlx.DB, table string, ranges map[string]qualityRange) ([]qualityIssue, error) {
+	issues := []qualityIssue{}
+	for column, r := range ranges {
+		conds := []string{}
+		args := []any{}
+		if r.Min != nil {
+			conds = append(conds, fmt.Sprintf("%s < ?", quoteIdent(column)))
+			args = append(args, r.Min)
+		}
+		if r.Max != nil {
+			conds = append(conds, fmt.Sprintf("%s > ?", quoteIdent(column)))
+			args = append(args, r.Max)
+		}
+		if len(conds) == 0 {
+			continue
+		}
+		var outside int64
+		if err := db.GetContext(ctx, &outside, fmt.Sprintf(
+			"SELECT COUNT_IF(%s) FROM %s", strings.Join(conds, " OR "), table,
+		), args...); err != nil {
+			return nil, fmt.Errorf("Failed to check range of %s: %w", column, err)
+		}
+		if outside > 0 {
+			issues = append(issues, qualityIssue{
+				Check:   qualityCheckRange,
+				Column:  column,
+				Message: fmt.Sprintf("%d values are out of range", outside),
+			})
+		}
+	}
+	return issues, nil
+}
+
+// toInt64 converts an integer scanned from Snowflake, which the driver may
+// return as a string, to an int64.
+func toInt64(v any) (int64, error) {
+	switch v := v.(type) {
+	case int64:
+		return v, nil
+	case string:
+		return strconv.ParseInt(v, 10, 64)
+	default:
+		return 0, fmt.Errorf("Unexpected integer value %v", v)
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// qualityTable answers the queries of the data quality checks for a table of
+// 10 rows with an ID primary key that has 2 duplicated values, an EMAIL
+// column that is 30% NULL, a NOTE column that is 80% NULL and 3 AGE values
+// out of range.
+func qualityTable(query string) fakeResult {
+	count := func(n int64) fakeResult {
+		return fakeResult{columns: []string{"COUNT(*)"}, rows: [][]driver.Value{{n}}}
+	}
+	switch {
+	case strings.HasPrefix(query, "SELECT COUNT(*) FROM (SELECT"):
+		return count(2)
+	case strings.HasPrefix(query, "SELECT COUNT(*) FROM"):
+		return count(10)
+	case strings.HasPrefix(query, "DESCRIBE TABLE"):
+		return fakeResult{
+			columns: []string{"name", "type", "kind", "null?"},
+			rows: [][]driver.Value{
+				{"ID", "NUMBER(38,0)", "COLUMN", "N"},
+				{"EMAIL", "VARCHAR(100)", "COLUMN", "Y"},
+				{"NOTE", "VARCHAR(100)", "COLUMN", "Y"},
+			},
+		}
+	case strings.HasPrefix(query, "SELECT COUNT("):
+		// Non-NULL counts are returned as strings like NUMBER values.
+		return fakeResult{columns: []string{"A", "B", "C"}, rows: [][]driver.Value{{"10", "7", "2"}}}
+	case strings.HasPrefix(query, "SHOW PRIMARY KEYS"):
+		return fakeResult{columns: []string{"column_name"}, rows: [][]driver.Value{{"ID"}}}
+	case strings.HasPrefix(query, "SELECT COUNT_IF("):
+		return count(3)
+	}
+	return fakeResult{err: fmt.Errorf("Unexpected query %q", query)}
+}
+
+func TestCheckDataQuality(t *testing.T) {
+	db, f := newFakeDB(t, qualityTable)
+	report, err := checkDataQuality(context.Background(), db, qualityRequest{
+		table:         "DB.PUBLIC.USERS",
+		checks:        []string{qualityCheckNulls, qualityCheckDuplicates, qualityCheckRange},
+		nullThreshold: 0.25,
+		ranges:        map[string]qualityRange{"AGE": {Min: 0, Max: 120}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []qualityIssue{
+		{Check: qualityCheckNulls, Column: "EMAIL", Message: "30.00% of values are NULL"},
+		{Check: qualityCheckNulls, Column: "NOTE", Message: "80.00% of values are NULL"},
+		{Check: qualityCheckDuplicates, Column: "ID", Message: "2 key values appear more than once"},
+		{Check: qualityCheckRange, Column: "AGE", Message: "3 values are out of range"},
+	}
+	if !reflect.DeepEqual(report["issues"], want) {
+		t.Errorf("Issues are %+v, want %+v", report["issues"], want)
+	}
+	if report["row_count"] != int64(10) {
+		t.Errorf("Row count is %v, want 10", report["row_count"])
+	}
+	for _, q := range f.ran() {
+		if !strings.Contains(q, `"DB"."PUBLIC"."USERS"`) {
+			t.Errorf("Query %q doesn't use the quoted table name", q)
+		}
+	}
+}
+
+func TestCheckDataQualityKeyColumns(t *testing.T) {
+	db, f := newFakeDB(t, qualityTable)
+	_, err := checkDataQuality(context.Background(), db, qualityRequest{
+		table:      "DB.PUBLIC.USERS",
+		checks:     []string{qualityCheckDuplicates},
+		keyColumns: []string{"EMAIL", "NOTE"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		`SELECT COUNT(*) FROM "DB"."PUBLIC"."USERS"`,
+		`SELECT COUNT(*) FROM (SELECT "EMAIL", "NOTE" FROM "DB"."PUBLIC"."USERS" GROUP BY "EMAIL", "NOTE" HAVING COUNT(*) > 1)`,
+	}
+	if !reflect.DeepEqual(f.ran(), want) {
+		t.Errorf("Ran %q, want %q", f.ran(), want)
+	}
+}
+
+func TestCheckDataQualityInvalidTable(t *testing.T) {
+	db, _ := newFakeDB(t, qualityTable)
+	if _, err := checkDataQuality(context.Background(), db, qualityRequest{table: "USERS"}); err == nil {
+		t.Error("checkDataQuality accepted an unqualified table name")
+	}
+}
+
+func TestToInt64(t *testing.T) {
+	tests := []struct {
+		v       any
+		want    int64
+		wantErr bool
+	}{
+		{int64(5), 5, false},
+		{"42", 42, false},
+		{"9223372036854775807", 9223372036854775807, false},
+		{"1.5", 0, true},
+		{1.5, 0, true},
+		{nil, 0, true},
+	}
+	for _, tt := range tests {
+		got, err := toInt64(tt.v)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("toInt64(%#v) = %d, %v", tt.v, got, err)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"time"
+)
+
+// queryLogEntry is a single line of the query log.
+type queryLogEntry struct {
+	Time      time.Time `json:"time"`
+	Query     string    `json:"query"`
+	ElapsedMS int64     `json:"elapsed_ms"`
+	RowCount  *int64    `json:"row_count,omitempty"`
+	QueryID   string    `json:"query_id,omitempty"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// queryLog appends executed queries to a writer as JSON lines. Entries are
+// written in the background so that logging never blocks query execution. A
+// nil *queryLog discards all entries.
+type queryLog struct {
+	entries chan queryLogEntry
+	done    chan struct{}
+}
+
+func newQueryLog(w io.Writer) *queryLog {
+	l := &queryLog{
+		entries: make(chan queryLogEntry, 1000),
+		done:    make(chan struct{}),
+	}
+	go func() {
+		defer close(l.done)
+		enc := json.NewEncoder(w)
+		for e := range l.entries {
+			if err := enc.Encode(e); err != nil {
+				slog.Error("Failed to write query log", "error", err)
+			}
+		}
+	}()
+	return l
+}
+
+// record logs the execution of query which started at start and produced
+// result or err. The entry is dropped if the log is falling behind.
+func (l *queryLog) record(start time.Time, query string, result map[string]any, err error) {
+	if l == nil {
+		return
+	}
+	e := queryLogEntry{
+		Time:      start,
+		Query:     query,
+		ElapsedMS: time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	switch n := result["row_count"].(type) {
+	case int:
+		c := int64(n)
+		e.RowCount = &c
+	}
+	switch n := result["rows_affected"].(type) {
+	case int64:
+		e.RowCount = &n
+	}
+	e.QueryID, _ = result["query_id"].(string)
+
+	select {
+	case l.entries <- e:
+	default:
+		slog.Warn("Query log is falling behind, dropped entry")
+	}
+}
+
+// close flushes pending entries.
+func (l *queryLog) close() {
+	if l == nil {
+		return
+	}
+	close(l.entries)
+	<-l.done
+}
@@ -0,0 +1,452 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// queryToolConfig holds the server options that apply to the tools running
+// queries.
+type queryToolConfig struct {
+	defaultFormat    string
+	nullString       string
+	truncateMode     string
+	sessionIsolation string
+	readOnly         bool
+	costWarnings     bool
+	// structuredOutput adds results in the shape of structuredResultSchema.
+	structuredOutput bool
+	// resultCache caches pages fetched by get_results, nil to disable.
+	resultCache *resultCache
+	// resultCursors tracks the results paged through with get_results.
+	resultCursors *resultCursors
+}
+
+// registerQueryTools registers the tools that run, check and explain SQL
+// given by the client.
+func registerQueryTools(tools *toolRegistry, db *sqlx.DB, runner *queryRunner, allowed databaseAllowlist, config queryToolConfig) {
+	structuredDescription := ""
+	if config.structuredOutput {
+		structuredDescription = structuredOutputDescription
+	}
+
+	// Add a query tool.
+	tools.add(mcp.NewTool(
+		"query",
+		mcp.WithDescription("Execute a SQL query."+structuredDescription),
+		mcp.WithString("query",
+			mcp.Required(),
+			mcp.Description("SQL query to execute.  You must use full database.schema.table when referencing tables."),
+		),
+		withProperty("params", map[string]any{
+			"type":        []string{"array", "object"},
+			"description": "Bind parameters for the query. Use an array for positional ? or :1 placeholders, or an object for :name placeholders. Values must be strings, numbers, booleans or null.",
+		}),
+		mcp.WithString("format",
+			mcp.Description("Format of the result. Markdown renders the rows as a table. Arrow returns the rows as a base64 encoded Arrow IPC stream which preserves types, for handing off to analytical tools."),
+			mcp.Enum(formatJSON, formatMarkdown, formatArrow),
+			mcp.DefaultString(config.defaultFormat),
+		),
+		mcp.WithBoolean("multi",
+			mcp.Description("Run multiple semicolon separated statements, e.g. USE SCHEMA x; SELECT ..., and return the result of each. Not supported with the arrow format."),
+			mcp.DefaultBool(false),
+		),
+		mcp.WithBoolean("compact",
+			mcp.Description("Return JSON without indentation to save tokens. Doesn't apply to the markdown format."),
+			mcp.DefaultBool(false),
+		),
+		mcp.WithBoolean("rows_only",
+			mcp.Description("Return just the rows as JSON lines, one array per row, without column info. Use it for repeated queries whose columns are already known. Only supported with the json format and a single statement."),
+			mcp.DefaultBool(false),
+		),
+		mcp.WithString("null_string",
+			mcp.Description("Text NULLs are rendered as in the markdown format, overriding the server default. The json format always uses null."),
+		),
+		mcp.WithString("warehouse",
+			mcp.Description("Warehouse to run the query on instead of the default one, e.g. a larger one for a heavy query. Only applies to this call."),
+		),
+		mcp.WithString("truncate_mode",
+			mcp.Description(fmt.Sprintf("What to do if the result has more than %d rows: truncate returns the first rows with a notice, error fails the query so that it can be narrowed down. Defaults to %s.", maxResultRows, config.truncateMode)),
+			mcp.Enum(truncateModeTruncate, truncateModeError),
+		),
+	), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		query, err := stringArg(request.Params.Arguments, "query", true)
+		if err != nil {
+			return nil, err
+		}
+		format, err := enumArg(request.Params.Arguments, "format", config.defaultFormat, formatJSON, formatMarkdown, formatArrow)
+		if err != nil {
+			return nil, err
+		}
+		multi, err := boolArg(request.Params.Arguments, "multi", false)
+		if err != nil {
+			return nil, err
+		}
+		opts := formatOptions{structured: config.structuredOutput}
+		if opts.compact, err = boolArg(request.Params.Arguments, "compact", false); err != nil {
+			return nil, err
+		}
+		if opts.rowsOnly, err = boolArg(request.Params.Arguments, "rows_only", false); err != nil {
+			return nil, err
+		}
+		if opts.nullString, err = nullStringArg(request.Params.Arguments, config.nullString); err != nil {
+			return nil, err
+		}
+		mode, err := enumArg(request.Params.Arguments, "truncate_mode", config.truncateMode, truncateModeTruncate, truncateModeError)
+		if err != nil {
+			return nil, err
+		}
+		runner := runner.withTruncateMode(mode)
+		if opts.rowsOnly && (format != formatJSON || multi) {
+			return nil, newArgError("Rows only is only supported with the json format and a single statement")
+		}
+		statements := []string{query}
+		if multi {
+			if format == formatArrow {
+				return nil, newArgError("The arrow format doesn't support multiple statements")
+			}
+			statements = splitStatements(query)
+		}
+		for _, stmt := range statements {
+			if config.readOnly && !isReadOnlyStatement(stmt) {
+				return nil, newArgError("Only read-only queries are allowed in read-only mode")
+			}
+		}
+		if err := allowed.checkQuery(query); err != nil {
+			return nil, err
+		}
+		args, err := bindParams(request.Params.Arguments["params"])
+		if err != nil {
+			return nil, err
+		}
+		warehouse, err := stringArg(request.Params.Arguments, "warehouse", false)
+		if err != nil {
+			return nil, err
+		}
+		if warehouse != "" {
+			if warehouse, err = parseIdent(warehouse); err != nil {
+				return nil, err
+			}
+		}
+		if config.sessionIsolation == sessionIsolationPerRequest {
+			var release func()
+			if runner, release, err = runner.isolated(ctx); err != nil {
+				return nil, err
+			}
+			defer release()
+		}
+		if warehouse != "" {
+			var release func()
+			if runner, release, err = runner.withWarehouse(ctx, warehouse); err != nil {
+				return nil, err
+			}
+			defer release()
+		}
+		if multi {
+			result, err := runner.runMultiQuery(ctx, query, len(statements), args...)
+			if err != nil {
+				return nil, err
+			}
+			return formatToolResult(result, format, opts)
+		}
+		if format == formatArrow {
+			result, err := runner.runArrow(ctx, query, args...)
+			if err != nil {
+				return nil, err
+			}
+			if config.costWarnings {
+				addCostWarnings(ctx, runner, result, query, args)
+			}
+			if opts.compact {
+				return compactJSONToolResult(result)
+			}
+			return jsonToolResult(result)
+		}
+		result, err := runner.runQuery(ctx, query, args...)
+		if err != nil {
+			return nil, err
+		}
+		if config.costWarnings {
+			addCostWarnings(ctx, runner, result, query, args)
+		}
+		return formatToolResult(result, format, opts)
+	})
+
+	// get_results doesn't support the arrow format.
+	resultsFormat := config.defaultFormat
+	if resultsFormat == formatArrow {
+		resultsFormat = formatJSON
+	}
+
+	// Add a tool to fetch the results of earlier queries. The query isn't
+	// run again, so it is allowed even in read-only mode.
+	tools.add(mcp.NewTool(
+		"get_results",
+		mcp.WithDescription(fmt.Sprintf("Fetch the results of a query run in the last 24 hours by its query ID, without running it again. Use offset to page through results longer than %d rows, and call close_results when done.", maxResultRows)+structuredDescription),
+		mcp.WithString("query_id",
+			mcp.Required(),
+			mcp.Description("Query ID as returned by the query tool or query_history."),
+		),
+		mcp.WithNumber("offset",
+			mcp.Description("Number of rows to skip."),
+			mcp.DefaultNumber(0),
+		),
+		mcp.WithString("format",
+			mcp.Description("Format of the result. Markdown renders the rows as a table."),
+			mcp.Enum(formatJSON, formatMarkdown),
+			mcp.DefaultString(resultsFormat),
+		),
+		mcp.WithString("null_string",
+			mcp.Description("Text NULLs are rendered as in the markdown format, overriding the server default."),
+		),
+	), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		queryID, err := stringArg(request.Params.Arguments, "query_id", true)
+		if err != nil {
+			return nil, err
+		}
+		offset, err := intArg(request.Params.Arguments, "offset", 0)
+		if err != nil {
+			return nil, err
+		}
+		format, err := enumArg(request.Params.Arguments, "format", resultsFormat, formatJSON, formatMarkdown)
+		if err != nil {
+			return nil, err
+		}
+		opts := formatOptions{structured: config.structuredOutput}
+		if opts.nullString, err = nullStringArg(request.Params.Arguments, config.nullString); err != nil {
+			return nil, err
+		}
+		result, err := getResults(ctx, runner, config.resultCache, config.resultCursors, queryID, offset)
+		if err != nil {
+			return nil, err
+		}
+		return formatToolResult(result, format, opts)
+	})
+
+	// Add a tool to release results paged through with get_results.
+	tools.add(mcp.NewTool(
+		"close_results",
+		mcp.WithDescription("Release the results of a query paged through with get_results once done with them, along with their cached pages. Closing results that aren't open is not an error."),
+		mcp.WithString("query_id",
+			mcp.Required(),
+			mcp.Description("Query ID as passed to get_results."),
+		),
+	), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		queryID, err := stringArg(request.Params.Arguments, "query_id", true)
+		if err != nil {
+			return nil, err
+		}
+		closed, err := closeResults(config.resultCache, config.resultCursors, queryID)
+		if err != nil {
+			return nil, err
+		}
+		if !closed {
+			return mcp.NewToolResultText(fmt.Sprintf("Results of query %s were not open", queryID)), nil
+		}
+		return mcp.NewToolResultText(fmt.Sprintf("Closed results of query %s", queryID)), nil
+	})
+
+	// Add an explain tool. EXPLAIN doesn't execute the query, so it is
+	// allowed even in read-only mode.
+	tools.add(mcp.NewTool(
+		"explain",
+		mcp.WithDescription("Get the execution plan of a SQL query without running it, to reason about partition pruning and join strategy."),
+		mcp.WithString("query",
+			mcp.Required(),
+			mcp.Description("SQL query to explain.  You must use full database.schema.table when referencing tables."),
+		),
+		mcp.WithString("explain_format",
+			mcp.Description("Format of the plan."),
+			mcp.Enum(explainText, explainJSON, explainTabular),
+			mcp.DefaultString(explainText),
+		),
+	), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		query, err := stringArg(request.Params.Arguments, "query", true)
+		if err != nil {
+			return nil, err
+		}
+		format, err := enumArg(request.Params.Arguments, "explain_format", explainText, explainText, explainJSON, explainTabular)
+		if err != nil {
+			return nil, err
+		}
+		if err := allowed.checkQuery(query); err != nil {
+			return nil, err
+		}
+		plan, err := explainQuery(ctx, runner, query, format)
+		if err != nil {
+			return nil, err
+		}
+		if format == explainTabular {
+			return jsonToolResult(plan)
+		}
+		return mcp.NewToolResultText(plan.(string)), nil
+	})
+
+	// Add a query cost tool, which is based on EXPLAIN and so also allowed
+	// in read-only mode.
+	tools.add(mcp.NewTool(
+		"query_cost",
+		mcp.WithDescription("Estimate the cost of a SQL query without running it, from the partitions and bytes its plan is expected to scan in total and per table. Use it to decide whether a query is cheap enough to run. Estimates Snowflake can't make, e.g. for metadata-only queries, are omitted."),
+		mcp.WithString("query",
+			mcp.Required(),
+			mcp.Description("SQL query to estimate.  You must use full database.schema.table when referencing tables."),
+		),
+	), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		query, err := stringArg(request.Params.Arguments, "query", true)
+		if err != nil {
+			return nil, err
+		}
+		if err := allowed.checkQuery(query); err != nil {
+			return nil, err
+		}
+		cost, err := estimateQueryCost(ctx, runner, query)
+		if err != nil {
+			return nil, err
+		}
+		return jsonToolResult(cost)
+	})
+
+	// Add a query validation tool. The query is only compiled, so it is
+	// allowed even in read-only mode.
+	tools.add(mcp.NewTool(
+		"validate_query",
+		mcp.WithDescription("Check whether a SQL query is valid without executing it. Returns the columns the query would produce or the Snowflake error."),
+		mcp.WithString("query",
+			mcp.Required(),
+			mcp.Description("SQL query to validate.  You must use full database.schema.table when referencing tables."),
+		),
+		withProperty("params", map[string]any{
+			"type":        []string{"array", "object"},
+			"description": "Bind parameters for the query. Use an array for positional ? or :1 placeholders, or an object for :name placeholders. Values must be strings, numbers, booleans or null.",
+		}),
+	), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		query, err := stringArg(request.Params.Arguments, "query", true)
+		if err != nil {
+			return nil, err
+		}
+		if err := allowed.checkQuery(query); err != nil {
+			return nil, err
+		}
+		args, err := bindParams(request.Params.Arguments["params"])
+		if err != nil {
+			return nil, err
+		}
+		return jsonToolResult(validateQuery(ctx, db, query, args...))
+	})
+
+	// Add a SQL formatting tool. It doesn't touch the database.
+	tools.add(mcp.NewTool(
+		"format_sql",
+		mcp.WithDescription("Format SQL consistently without running it: keywords in one case, each clause on its own line and subqueries indented. Literals, quoted identifiers and comments are kept as is."),
+		mcp.WithString("sql",
+			mcp.Required(),
+			mcp.Description("SQL to format, one or more statements."),
+		),
+		mcp.WithString("keyword_case",
+			mcp.Description("Case of keywords."),
+			mcp.Enum("upper", "lower"),
+			mcp.DefaultString("upper"),
+		),
+	), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		statement, err := stringArg(request.Params.Arguments, "sql", true)
+		if err != nil {
+			return nil, err
+		}
+		keywordCase, err := enumArg(request.Params.Arguments, "keyword_case", "upper", "upper", "lower")
+		if err != nil {
+			return nil, err
+		}
+		formatted, err := formatSQL(statement, keywordCase == "upper")
+		if err != nil {
+			return nil, err
+		}
+		return mcp.NewToolResultText(formatted), nil
+	})
+
+	// Add an execute tool for statements that modify data. It is disabled in
+	// read-only mode.
+	tools.add(mcp.NewTool(
+		"execute",
+		mcp.WithDescription("Execute a DDL or DML statement such as INSERT, UPDATE, DELETE or CREATE and return the number of affected rows. Use the query tool for statements that return results."),
+		mcp.WithString("statement",
+			mcp.Required(),
+			mcp.Description("SQL statement to execute.  You must use full database.schema.table when referencing tables."),
+		),
+		withProperty("params", map[string]any{
+			"type":        []string{"array", "object"},
+			"description": "Bind parameters for the statement. Use an array for positional ? or :1 placeholders, or an object for :name placeholders. Values must be strings, numbers, booleans or null.",
+		}),
+	), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		statement, err := stringArg(request.Params.Arguments, "statement", true)
+		if err != nil {
+			return nil, err
+		}
+		if err := allowed.checkQuery(statement); err != nil {
+			return nil, err
+		}
+		args, err := bindParams(request.Params.Arguments["params"])
+		if err != nil {
+			return nil, err
+		}
+		runner := runner
+		if config.sessionIsolation == sessionIsolationPerRequest {
+			var release func()
+			if runner, release, err = runner.isolated(ctx); err != nil {
+				return nil, err
+			}
+			defer release()
+		}
+		result, err := runner.runExec(ctx, statement, args...)
+		if err != nil {
+			return nil, err
+		}
+		return jsonToolResult(result)
+	})
+
+	// Add a DML preview tool. It only runs SELECTs, so it is allowed even in
+	// read-only mode.
+	tools.add(mcp.NewTool(
+		"preview_dml",
+		mcp.WithDescription("Preview the rows an UPDATE or DELETE statement would affect, without running it. Returns the number of affected rows and a sample of them. Use it before running destructive statements with execute."),
+		mcp.WithString("statement",
+			mcp.Required(),
+			mcp.Description("DELETE FROM or UPDATE statement to preview. Statements joining other tables with DELETE ... USING or UPDATE ... FROM are not supported."),
+		),
+		withProperty("params", map[string]any{
+			"type":        []string{"array", "object"},
+			"description": "Bind parameters for the WHERE clause of the statement. Use an array for positional ? or :1 placeholders, or an object for :name placeholders. Placeholders in the SET clause of an UPDATE are dropped along with it, so only :name placeholders work there.",
+		}),
+		mcp.WithNumber("limit",
+			mcp.Description(fmt.Sprintf("Number of affected rows to return, at most %d.", maxResultRows)),
+			mcp.DefaultNumber(10),
+		),
+	), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		statement, err := stringArg(request.Params.Arguments, "statement", true)
+		if err != nil {
+			return nil, err
+		}
+		limit, err := intArg(request.Params.Arguments, "limit", 10)
+		if err != nil {
+			return nil, err
+		}
+		if limit < 1 || limit > maxResultRows {
+			return nil, newArgError("Limit must be between 1 and %d", maxResultRows)
+		}
+		if err := allowed.checkQuery(statement); err != nil {
+			return nil, err
+		}
+		args, err := bindParams(request.Params.Arguments["params"])
+		if err != nil {
+			return nil, err
+		}
+		result, err := previewDML(ctx, runner, statement, limit, args...)
+		if err != nil {
+			return nil, err
+		}
+		return jsonToolResult(result)
+	})
+}
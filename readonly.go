@@ -0,0 +1,122 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+)
+
+// readOnlyKeywords are the leading keywords of statements allowed in
+// read-only mode.
+var readOnlyKeywords = map[string]bool{
+	"SELECT":   true,
+	"WITH":     true,
+	"SHOW":     true,
+	"DESCRIBE": true,
+	"DESC":     true,
+	"EXPLAIN":  true,
+	"LIST":     true,
+	"LS":       true,
+}
+
+// isReadOnlyStatement makes a best effort guess at whether query only reads
+// data, based on its leading keyword.
+func isReadOnlyStatement(query string) bool {
+	return readOnlyKeywords[leadingKeyword(query)]
+}
+
+// leadingKeyword returns the first keyword of query in upper case, skipping
+// whitespace, comments and opening parentheses.
+func leadingKeyword(query string) string {
+	for {
+		query = strings.TrimLeftFunc(query, func(r rune) bool {
+			return unicode.IsSpace(r) || r == '('
+		})
+		switch {
+		case strings.HasPrefix(query, "--"), strings.HasPrefix(query, "//"):
+			i := strings.IndexByte(query, '\n')
+			if i < 0 {
+				return ""
+			}
+			query = query[i+1:]
+		case strings.HasPrefix(query, "/*"):
+			i := strings.Index(query, "*/")
+			if i < 0 {
+				return ""
+			}
+			query = query[i+2:]
+		default:
+			end := strings.IndexFunc(query, func(r rune) bool {
+				return !unicode.IsLetter(r) && r != '_'
+			})
+			if end < 0 {
+				end = len(query)
+			}
+			return strings.ToUpper(query[:end])
+		}
+	}
+}
+
+// splitStatements splits query into statements at semicolons outside of
+// literals, quoted identifiers and comments. Empty statements are dropped.
+func splitStatements(query string) []string {
+	statements := []string{}
+	add := func(s string) {
+		if s = strings.TrimSpace(s); s != "" {
+			statements = append(statements, s)
+		}
+	}
+	begin := 0
+	for i := 0; i < len(query); {
+		switch {
+		case query[i] == '\'' || query[i] == '"':
+			q := query[i]
+			i++
+			for i < len(query) {
+				if query[i] == '\\' && q == '\'' {
+					i += 2
+					continue
+				}
+				if query[i] == q {
+					if i+1 < len(query) && query[i+1] == q {
+						i += 2
+						continue
+					}
+					break
+				}
+				i++
+			}
+			i++
+		case strings.HasPrefix(query[i:], "$$"):
+			j := strings.Index(query[i+2:], "$$")
+			if j < 0 {
+				i = len(query)
+			} else {
+				i += j + 4
+			}
+		case strings.HasPrefix(query[i:], "--"), strings.HasPrefix(query[i:], "//"):
+			j := strings.IndexByte(query[i:], '\n')
+			if j < 0 {
+				i = len(query)
+			} else {
+				i += j + 1
+			}
+		case strings.HasPrefix(query[i:], "/*"):
+			j := strings.Index(query[i+2:], "*/")
+			if j < 0 {
+				i = len(query)
+			} else {
+				i += j + 4
+			}
+		case query[i] == ';':
+			add(query[begin:i])
+			i++
+			begin = i
+		default:
+			i++
+		}
+	}
+	if begin < len(query) {
+		add(query[begin:])
+	}
+	return statements
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitStatements(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"SELECT 1", []string{"SELECT 1"}},
+		{"SELECT 1;", []string{"SELECT 1"}},
+		{"SELECT 1; SELECT 2", []string{"SELECT 1", "SELECT 2"}},
+		{" ; ;SELECT 1;; ", []string{"SELECT 1"}},
+		{"", []string{}},
+		{"SELECT 'a;b'; SELECT 2", []string{"SELECT 'a;b'", "SELECT 2"}},
+		{"SELECT 'it''s;'; SELECT 2", []string{"SELECT 'it''s;'", "SELECT 2"}},
+		{`SELECT 'a\';b'; SELECT 2`, []string{`SELECT 'a\';b'`, "SELECT 2"}},
+		{`SELECT "a;b" FROM t; SELECT 2`, []string{`SELECT "a;b" FROM t`, "SELECT 2"}},
+		{"SELECT $$a;b$$; SELECT 2", []string{"SELECT $$a;b$$", "SELECT 2"}},
+		{"SELECT 1 -- a;b\n; SELECT 2", []string{"SELECT 1 -- a;b", "SELECT 2"}},
+		{"SELECT 1 // a;b\n; SELECT 2", []string{"SELECT 1 // a;b", "SELECT 2"}},
+		{"SELECT /* a;b */ 1; SELECT 2", []string{"SELECT /* a;b */ 1", "SELECT 2"}},
+		{"SELECT 'unterminated;", []string{"SELECT 'unterminated;"}},
+		{"SELECT 1 /* unterminated;", []string{"SELECT 1 /* unterminated;"}},
+	}
+	for _, tt := range tests {
+		if got := splitStatements(tt.query); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitStatements(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestIsReadOnlyStatement(t *testing.T) {
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{"SELECT 1", true},
+		{"  select * from t", true},
+		{"WITH x AS (SELECT 1) SELECT * FROM x", true},
+		{"(SELECT 1) UNION (SELECT 2)", true},
+		{"-- comment\nSHOW TABLES", true},
+		{"/* comment */ DESCRIBE TABLE t", true},
+		{"desc table t", true},
+		{"EXPLAIN SELECT 1", true},
+		{"LIST @stage", true},
+		{"INSERT INTO t VALUES (1)", false},
+		{"DELETE FROM t", false},
+		{"-- SELECT\nDROP TABLE t", false},
+		{"/* SELECT */ UPDATE t SET a = 1", false},
+		{"SELECTX 1", false},
+		{"", false},
+		{"-- only a comment", false},
+	}
+	for _, tt := range tests {
+		if got := isReadOnlyStatement(tt.query); got != tt.want {
+			t.Errorf("isReadOnlyStatement(%q) = %t, want %t", tt.query, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// resourceMiddleware wraps resource handlers with caching, database access
+// checks and retries.
+type resourceMiddleware struct {
+	cache   *resourceCache
+	allowed databaseAllowlist
+	retry   retryPolicy
+}
+
+// wrap returns h wrapped with the middleware.
+func (m resourceMiddleware) wrap(h func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error)) func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	return m.cache.wrap(m.allowed.wrap(m.retry.wrap(h)))
+}
+
+// addResourceTemplate registers a resource template along with a variant of
+// it ending in a slash. mcp-go only dispatches URIs matching a template
+// exactly, so URIs with a trailing slash need a template of their own.
+func addResourceTemplate(s *server.MCPServer, template mcp.ResourceTemplate, handler server.ResourceTemplateHandlerFunc) {
+	s.AddResourceTemplate(template, handler)
+	template.URITemplate += "/"
+	s.AddResourceTemplate(template, handler)
+}
+
+// addDescribeResource registers a resource at
+// snowflake://{database-name}/{schema-name}/<itemPath>/{name} with the
+// properties of the object reported by the describe command run on it.
+func addDescribeResource(s *server.MCPServer, mw resourceMiddleware, db *sqlx.DB, itemPath, describe, name, description string) {
+	pat := regexp.MustCompile(`^snowflake://([^/]+)/([^/]+)/` + regexp.QuoteMeta(itemPath) + `/([^/]+)$`)
+	addResourceTemplate(s, mcp.NewResourceTemplate(
+		"snowflake://{database-name}/{schema-name}/"+itemPath+"/{name}",
+		name,
+		mcp.WithTemplateDescription(description),
+		mcp.WithTemplateMIMEType("application/json"),
+	), mw.wrap(func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		m, err := matchURI(pat, request.Params.URI)
+		if err != nil {
+			return nil, err
+		}
+		if m == nil {
+			return nil, fmt.Errorf("Invalid URI")
+		}
+		props, err := describeObject(ctx, db, describe, fmt.Sprintf("%s.%s.%s", sqlIdent(m[1]), sqlIdent(m[2]), sqlIdent(m[3])))
+		if err != nil {
+			return nil, err
+		}
+		return jsonResourceContents(request.Params.URI, props)
+	}))
+}
+
+// systemDatabases are databases shared with every account which are hidden
+// from the database list unless asked for.
+var systemDatabases = map[string]bool{
+	"SNOWFLAKE":             true,
+	"SNOWFLAKE_SAMPLE_DATA": true,
+}
+
+// addResources registers all resources. If hideSystem is set, system
+// databases are left out of the database list but remain accessible.
+func addResources(s *server.MCPServer, mw resourceMiddleware, db *sqlx.DB, hideSystem bool) {
+	s.AddResource(mcp.NewResource(
+		"snowflake://",
+		"Database list",
+		mcp.WithResourceDescription("List of databases"),
+		mcp.WithMIMEType("text/plain"),
+	), mw.wrap(func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		names, err := getNameList(ctx, db, "SHOW TERSE DATABASES", func(name string) string { return name })
+		if err != nil {
+			return nil, err
+		}
+		contents := []mcp.ResourceContents{}
+		for _, name := range names {
+			if mw.allowed.check(name) != nil || hideSystem && systemDatabases[name] {
+				continue
+			}
+			contents = append(contents, mcp.TextResourceContents{
+				URI:      resourceURI(name),
+				MIMEType: "text/plain",
+				Text:     name,
+			})
+		}
+		return contents, nil
+	}))
+
+	addInfoResource(s, db)
+
+	schemaPat := regexp.MustCompile(`^snowflake://([^/]+)$`)
+	addListingTemplate(s,
+		"snowflake://{database-name}",
+		"Schema list in database",
+		"List of schemas in a database",
+		mw.wrap(func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+			uri, page, err := parseListingURI(request.Params.URI)
+			if err != nil {
+				return nil, err
+			}
+			m, err := matchURI(schemaPat, uri)
+			if err != nil {
+				return nil, err
+			}
+			if m == nil {
+				return nil, fmt.Errorf("Invalid URI")
+			}
+			dbName := m[1]
+			return getNamePage(ctx, db, fmt.Sprintf(`SHOW TERSE SCHEMAS IN DATABASE %s`, sqlIdent(dbName)), uri, page, func(name string) mcp.ResourceContents {
+				return mcp.TextResourceContents{
+					URI:      resourceURI(dbName, name),
+					MIMEType: "text/plain",
+					Text:     name,
+				}
+			})
+		}),
+	)
+
+	addSchemaListing(s, mw, db, "tables", "table", "SHOW TERSE TABLES", "Table list in schema", "List of tables in a schema")
+	addSchemaListing(s, mw, db, "views", "view", "SHOW TERSE VIEWS", "View list in schema", "List of views in a schema")
+	addSchemaListing(s, mw, db, "materialized-views", "materialized-view", "SHOW MATERIALIZED VIEWS", "Materialized view list in schema", "List of materialized views in a schema")
+	addObjectListing(s, mw, db)
+	addSchemaListing(s, mw, db, "external-tables", "external-table", "SHOW TERSE EXTERNAL TABLES", "External table list in schema", "List of external tables in a schema")
+	addSchemaListing(s, mw, db, "stages", "stage", "SHOW STAGES", "Stage list in schema", "List of stages in a schema")
+	addStageResources(s, mw, db)
+	addSchemaListing(s, mw, db, "sequences", "sequence", "SHOW SEQUENCES", "Sequence list in schema", "List of sequences in a schema")
+	addDescribeResource(s, mw, db, "sequence", "DESCRIBE SEQUENCE", "Sequence definition", "Definition of a sequence including its next value and increment")
+	addSchemaListing(s, mw, db, "tasks", "task", "SHOW TERSE TASKS", "Task list in schema", "List of tasks in a schema. Tasks the role has no privileges on are not listed")
+	addDescribeResource(s, mw, db, "task", "DESCRIBE TASK", "Task definition", "Definition of a task including its schedule, state (started or suspended) and the SQL it runs")
+	addPipeResources(s, mw, db)
+
+	showCommands := map[string]string{
+		"table":             "SHOW TABLES",
+		"view":              "SHOW VIEWS",
+		"materialized-view": "SHOW MATERIALIZED VIEWS",
+		"external-table":    "SHOW EXTERNAL TABLES",
+	}
+	defPat := regexp.MustCompile(`^snowflake://([^/]+)/([^/]+)/(view|table|materialized-view|external-table)/([^/]+)$`)
+	vtDefHandler := func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		m, err := matchURI(defPat, request.Params.URI)
+		if err != nil {
+			return nil, err
+		}
+		if m == nil {
+			return nil, fmt.Errorf("Invalid URI")
+		}
+		dbName, schemaName, kind, tableName := m[1], m[2], m[3], m[4]
+		columns, err := describeTable(ctx, db, fmt.Sprintf("%s.%s.%s", sqlIdent(dbName), sqlIdent(schemaName), sqlIdent(tableName)))
+		if err != nil {
+			return nil, err
+		}
+		def := map[string]any{
+			"columns": columns,
+		}
+
+		// The comment and, for tables, the row count come from metadata.
+		// Failing to get them shouldn't fail the whole definition.
+		if info, err := showTable(ctx, db, showCommands[kind], dbName, schemaName, tableName); err != nil {
+			slog.Warn("Failed to get metadata", "table", fmt.Sprintf("%s.%s.%s", dbName, schemaName, tableName), "error", err)
+		} else {
+			if info.Comment.Valid && info.Comment.String != "" {
+				def["comment"] = info.Comment.String
+			}
+			// Row counts are only meaningful for tables.
+			if kind == "table" && info.Rows.Valid {
+				def["row_count"] = info.Rows.Int64
+				def["row_count_note"] = "Estimate from table metadata, use a COUNT(*) query for an exact count"
+			}
+		}
+
+		return jsonResourceContents(request.Params.URI, def)
+	}
+
+	addResourceTemplate(s, mcp.NewResourceTemplate(
+		"snowflake://{database-name}/{schema-name}/table/{table-name}",
+		"Table definition",
+		mcp.WithTemplateDescription("Definition of a table including columns, column types and comments"),
+		mcp.WithTemplateMIMEType("application/json"),
+	), mw.wrap(vtDefHandler))
+
+	addResourceTemplate(s, mcp.NewResourceTemplate(
+		"snowflake://{database-name}/{schema-name}/view/{table-name}",
+		"View definition",
+		mcp.WithTemplateDescription("Definition of a view including columns, column types and comments"),
+		mcp.WithTemplateMIMEType("application/json"),
+	), mw.wrap(vtDefHandler))
+
+	addResourceTemplate(s, mcp.NewResourceTemplate(
+		"snowflake://{database-name}/{schema-name}/materialized-view/{table-name}",
+		"Materialized view definition",
+		mcp.WithTemplateDescription("Definition of a materialized view including columns, column types and comments"),
+		mcp.WithTemplateMIMEType("application/json"),
+	), mw.wrap(vtDefHandler))
+
+	addResourceTemplate(s, mcp.NewResourceTemplate(
+		"snowflake://{database-name}/{schema-name}/external-table/{table-name}",
+		"External table definition",
+		mcp.WithTemplateDescription("Definition of an external table including columns, column types and comments"),
+		mcp.WithTemplateMIMEType("application/json"),
+	), mw.wrap(vtDefHandler))
+}
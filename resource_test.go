@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// readResource reads uri from the resources registered by addResources,
+// answering their queries with respond.
+func readResource(t *testing.T, uri string, respond func(query string) fakeResult) ([]mcp.TextResourceContents, *fakeDB) {
+	db, f := newFakeDB(t, respond)
+	s := server.NewMCPServer("test", "test", server.WithResourceCapabilities(false, false))
+	addResources(s, resourceMiddleware{}, db, true)
+	msg, err := json.Marshal(map[string]any{
+		"jsonrpc": "2.0",
+		"id":      1,
+		"method":  "resources/read",
+		"params":  map[string]any{"uri": uri},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	reply := s.HandleMessage(context.Background(), msg)
+	res, ok := reply.(mcp.JSONRPCResponse)
+	if !ok {
+		t.Fatalf("Reading %s failed: %+v", uri, reply)
+	}
+	contents := []mcp.TextResourceContents{}
+	for _, c := range res.Result.(mcp.ReadResourceResult).Contents {
+		contents = append(contents, c.(mcp.TextResourceContents))
+	}
+	return contents, f
+}
+
+// showObjects answers SHOW commands listing tables and views in a schema
+// holding the tables ORDERS and CUSTOMERS and the view ORDER_SUMMARY.
+func showObjects(query string) fakeResult {
+	r := fakeResult{columns: []string{"name", "kind"}}
+	switch {
+	case strings.HasPrefix(query, "SHOW TERSE TABLES"):
+		r.rows = [][]driver.Value{{"ORDERS", "TABLE"}, {"CUSTOMERS", "TABLE"}}
+	case strings.HasPrefix(query, "SHOW TERSE VIEWS"):
+		r.rows = [][]driver.Value{{"ORDER_SUMMARY", "VIEW"}}
+	default:
+		r.err = fmt.Errorf("Unexpected query %q", query)
+	}
+	return r
+}
+
+func TestViewsResourceListsViews(t *testing.T) {
+	contents, f := readResource(t, "snowflake://DB/PUBLIC/views", showObjects)
+	if want := []string{"SHOW TERSE VIEWS IN SCHEMA DB.PUBLIC"}; !reflect.DeepEqual(f.ran(), want) {
+		t.Errorf("Ran %q, want %q", f.ran(), want)
+	}
+	want := []mcp.TextResourceContents{{
+		URI:      "snowflake://DB/PUBLIC/view/ORDER_SUMMARY",
+		MIMEType: "text/plain",
+		Text:     "ORDER_SUMMARY",
+	}}
+	if !reflect.DeepEqual(contents, want) {
+		t.Errorf("Got %+v, want %+v", contents, want)
+	}
+}
+
+func TestTablesResourceListsTables(t *testing.T) {
+	contents, _ := readResource(t, "snowflake://DB/PUBLIC/tables", showObjects)
+	got := []string{}
+	for _, c := range contents {
+		got = append(got, c.URI)
+	}
+	want := []string{"snowflake://DB/PUBLIC/table/ORDERS", "snowflake://DB/PUBLIC/table/CUSTOMERS"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Got %q, want %q", got, want)
+	}
+}
+
+func TestPaginatedListings(t *testing.T) {
+	respond := func(query string) fakeResult {
+		return fakeResult{
+			columns: []string{"name"},
+			rows:    [][]driver.Value{{"A"}, {"B"}, {"C"}},
+		}
+	}
+	tests := []struct {
+		uri   string
+		query string
+	}{
+		{"snowflake://DB/PUBLIC/tables?offset=1&limit=1", "SHOW TERSE TABLES IN SCHEMA DB.PUBLIC LIMIT 3"},
+		// These SHOW commands don't accept LIMIT.
+		{"snowflake://DB/PUBLIC/stages?offset=1&limit=1", "SHOW STAGES IN SCHEMA DB.PUBLIC"},
+		{"snowflake://DB/PUBLIC/sequences?offset=1&limit=1", "SHOW SEQUENCES IN SCHEMA DB.PUBLIC"},
+		{"snowflake://DB/PUBLIC/pipes?offset=1&limit=1", "SHOW PIPES IN SCHEMA DB.PUBLIC"},
+		{"snowflake://DB/PUBLIC/materialized-views?offset=1&limit=1", "SHOW MATERIALIZED VIEWS IN SCHEMA DB.PUBLIC"},
+	}
+	for _, tt := range tests {
+		contents, f := readResource(t, tt.uri, respond)
+		if want := []string{tt.query}; !reflect.DeepEqual(f.ran(), want) {
+			t.Errorf("%s ran %q, want %q", tt.uri, f.ran(), want)
+		}
+		texts := []string{}
+		for _, c := range contents {
+			texts = append(texts, c.Text)
+		}
+		base, _, _ := strings.Cut(tt.uri, "?")
+		if want := []string{"B", "More results available at " + base + "?offset=2&limit=1"}; !reflect.DeepEqual(texts, want) {
+			t.Errorf("%s returned %q, want %q", tt.uri, texts, want)
+		}
+	}
+}
+
+func TestListingCancel(t *testing.T) {
+	db, _ := newFakeDB(t, func(string) fakeResult { return fakeResult{block: true} })
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := getNamePage(ctx, db, "SHOW TERSE TABLES IN SCHEMA DB.PUBLIC", "snowflake://DB/PUBLIC/tables", listingPage{}, func(name string) mcp.ResourceContents {
+		return mcp.TextResourceContents{Text: name}
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Got error %v, want the listing to stop at the deadline", err)
+	}
+}
@@ -0,0 +1,633 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/snowflakedb/gosnowflake"
+)
+
+// maxResultRows is the maximum number of rows returned from a single query.
+const maxResultRows = 1000
+
+// resultOptions controls how query results are serialized.
+type resultOptions struct {
+	// floatPrecision is the number of significant digits FLOAT values are
+	// rounded to. Zero disables rounding.
+	floatPrecision int
+	// maxCellBytes is the length in bytes above which string and binary
+	// values are truncated. Zero disables truncation.
+	maxCellBytes int
+	// maxResponseBytes is the budget for the JSON encoded rows of a result.
+	// Zero disables the budget.
+	maxResponseBytes int
+	// maxArrowBytes is the largest encoded size of Arrow results. Zero
+	// disables the limit.
+	maxArrowBytes int
+}
+
+// convertValue converts a value scanned from a column of type dbType for
+// inclusion in the result. Numbers are kept exact, dates and times are
+// formatted as RFC 3339 strings, in UTC unless they carry their own offset,
+// and semi-structured values are parsed into nested JSON.
+func (o resultOptions) convertValue(v any, dbType string) any {
+	switch v := v.(type) {
+	case float64:
+		// Only true floats are rounded. NUMBER/DECIMAL values are returned by
+		// the driver as exact strings and are left alone.
+		if o.floatPrecision > 0 && dbType == "REAL" {
+			r, err := strconv.ParseFloat(strconv.FormatFloat(v, 'g', o.floatPrecision, 64), 64)
+			if err == nil {
+				return r
+			}
+		}
+		if dbType == "FIXED" {
+			return strconv.FormatFloat(v, 'f', -1, 64)
+		}
+	case int64:
+		if dbType == "FIXED" {
+			return strconv.FormatInt(v, 10)
+		}
+	case *big.Int:
+		return v.String()
+	case *big.Float:
+		return v.Text('f', -1)
+	case time.Time:
+		switch dbType {
+		case "DATE":
+			return v.Format(time.DateOnly)
+		case "TIME":
+			return v.Format("15:04:05.999999999")
+		case "TIMESTAMP_NTZ", "TIMESTAMP_LTZ":
+			// NTZ values are read as UTC and LTZ values are normalized to
+			// UTC so that they don't depend on the session time zone.
+			return v.UTC().Format(time.RFC3339Nano)
+		}
+		// TIMESTAMP_TZ values keep their own offset.
+		return v.Format(time.RFC3339Nano)
+	case string:
+		switch dbType {
+		case "VARIANT", "OBJECT", "ARRAY":
+			// Fall back to the raw string if it isn't valid JSON.
+			if parsed, err := parseJSONExact(v); err == nil {
+				return parsed
+			}
+		}
+	}
+	return v
+}
+
+// parseJSONExact parses s as a single JSON value, keeping numbers as
+// json.Number so that integers beyond 2^53 aren't rounded.
+func parseJSONExact(s string) (any, error) {
+	dec := json.NewDecoder(strings.NewReader(s))
+	dec.UseNumber()
+	var parsed any
+	if err := dec.Decode(&parsed); err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, fmt.Errorf("Unexpected data after JSON value")
+	}
+	return parsed, nil
+}
+
+// normalizeNull returns nil for any representation of a NULL value the
+// driver may scan, such as a nil byte slice or pointer, so that NULLs are
+// consistently encoded as JSON null.
+func normalizeNull(v any) any {
+	switch x := v.(type) {
+	case nil:
+		return nil
+	case []byte:
+		if x == nil {
+			return nil
+		}
+	case driver.Valuer:
+		// E.g. sql.NullString.
+		if rv := reflect.ValueOf(x); rv.Kind() == reflect.Pointer && rv.IsNil() {
+			return nil
+		}
+		if dv, err := x.Value(); err == nil && dv == nil {
+			return nil
+		}
+	default:
+		if rv := reflect.ValueOf(x); rv.Kind() == reflect.Pointer && rv.IsNil() {
+			return nil
+		}
+	}
+	return v
+}
+
+// sanitizeCell replaces invalid UTF-8 and control characters other than tab
+// and line breaks in string values with U+FFFD, reporting whether anything
+// was replaced. Such values, e.g. from corrupt data, would otherwise garble
+// markdown output or confuse clients.
+func sanitizeCell(v any) (any, bool) {
+	s, ok := v.(string)
+	if !ok {
+		return v, false
+	}
+	if utf8.ValidString(s) && strings.IndexFunc(s, isUnsafeRune) < 0 {
+		return v, false
+	}
+	return strings.Map(func(r rune) rune {
+		if isUnsafeRune(r) {
+			return utf8.RuneError
+		}
+		return r
+	}, strings.ToValidUTF8(s, string(utf8.RuneError))), true
+}
+
+// isUnsafeRune reports whether r is a control character other than tab and
+// line breaks.
+func isUnsafeRune(r rune) bool {
+	return unicode.IsControl(r) && r != '\t' && r != '\n' && r != '\r'
+}
+
+// truncateCell truncates string and binary values longer than maxBytes,
+// reporting whether truncation occurred. Truncated strings end with a marker
+// stating how many bytes were dropped.
+func truncateCell(v any, maxBytes int) (any, bool) {
+	if maxBytes <= 0 {
+		return v, false
+	}
+	switch v := v.(type) {
+	case string:
+		if len(v) <= maxBytes {
+			return v, false
+		}
+		// Don't cut a multi-byte character in half.
+		n := maxBytes
+		for n > 0 && !utf8.RuneStart(v[n]) {
+			n--
+		}
+		return fmt.Sprintf("%s…[truncated %d bytes]", v[:n], len(v)-n), true
+	case []byte:
+		if len(v) <= maxBytes {
+			return v, false
+		}
+		return v[:maxBytes], true
+	}
+	return v, false
+}
+
+// queryRunner runs SQL on behalf of tools and serializes the results.
+type queryRunner struct {
+	db   *sqlx.DB
+	opts resultOptions
+	log  *queryLog
+	// autoLimit makes simple SELECT queries without a LIMIT fetch at most
+	// one row more than is returned.
+	autoLimit bool
+	// retry is the policy for retrying read-only queries.
+	retry retryPolicy
+	// reportTotal makes runQuery count the rows of cut off SELECT queries.
+	reportTotal bool
+	// cutOffError makes queries fail instead of returning results that are
+	// cut off.
+	cutOffError bool
+	// conn, if set, is the connection queries run on instead of the pool.
+	conn *sqlx.Conn
+}
+
+// sqlQueryer is implemented by both the connection pool and a single
+// connection.
+type sqlQueryer interface {
+	QueryxContext(ctx context.Context, query string, args ...any) (*sqlx.Rows, error)
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	GetContext(ctx context.Context, dest any, query string, args ...any) error
+}
+
+// queryer returns where queries are run.
+func (r *queryRunner) queryer() sqlQueryer {
+	if r.conn != nil {
+		return r.conn
+	}
+	return r.db
+}
+
+// Truncate modes of the query tool, telling what to do with results over
+// the row cap.
+const (
+	truncateModeTruncate = "truncate"
+	truncateModeError    = "error"
+)
+
+// withTruncateMode returns a copy of r that handles results over the row cap
+// according to mode.
+func (r *queryRunner) withTruncateMode(mode string) *queryRunner {
+	c := *r
+	c.cutOffError = mode == truncateModeError
+	return &c
+}
+
+// checkCutOff returns an error if res was cut off and r is set to fail on
+// that.
+func (r *queryRunner) checkCutOff(res *rawResult) error {
+	if !r.cutOffError || !(res.more || res.overBudget) {
+		return nil
+	}
+	return newArgError("The result has more rows than the %d that can be returned. Add a LIMIT or narrow the query down, e.g. with a WHERE clause or aggregation", len(res.rows))
+}
+
+// rawResult is the result of a query as returned by the driver.
+type rawResult struct {
+	columnTypes []*sql.ColumnType
+	rows        [][]any
+	// more is whether there were rows beyond the first maxResultRows.
+	more bool
+	// truncated is whether any converted values were truncated.
+	truncated bool
+	// sanitized is whether any converted values had characters replaced.
+	sanitized bool
+	// overBudget is whether fetching stopped at the response byte budget.
+	overBudget bool
+	queryID    string
+	// next holds the further result sets of statements returning more than
+	// one, e.g. some stored procedure calls.
+	next []*rawResult
+}
+
+// limit adds a LIMIT to query if automatic limits are enabled and it is safe
+// to do so.
+func (r *queryRunner) limit(query string) string {
+	if r.autoLimit {
+		query, _ = addLimit(query, maxResultRows+1)
+	}
+	return query
+}
+
+// fetch executes query and returns its column types and up to maxResultRows
+// rows. If convert is set, values are sanitized, converted and truncated
+// according to the result options and fetching stops once the rows exceed
+// the response byte budget. Read-only queries are retried on transient failures.
+func (r *queryRunner) fetch(ctx context.Context, convert bool, query string, args ...any) (res *rawResult, err error) {
+	if !isReadOnlyStatement(query) {
+		return r.fetchOnce(ctx, convert, query, args...)
+	}
+	err = r.retry.do(ctx, func() error {
+		res, err = r.fetchOnce(ctx, convert, query, args...)
+		return err
+	})
+	return res, err
+}
+
+func (r *queryRunner) fetchOnce(ctx context.Context, convert bool, query string, args ...any) (*rawResult, error) {
+	// Execute the query, capturing the Snowflake query ID when the driver
+	// reports one.
+	queryIDChan := make(chan string, 1)
+	rows, err := r.queryer().QueryxContext(gosnowflake.WithQueryIDChan(ctx, queryIDChan), query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to execute query: %w", err)
+	}
+	defer rows.Close()
+	res, err := r.readResultSet(rows, convert)
+	if err != nil {
+		return nil, err
+	}
+	for rows.NextResultSet() {
+		next, err := r.readResultSet(rows, convert)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read result set %d: %w", len(res.next)+2, err)
+		}
+		res.next = append(res.next, next)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("Failed to fetch rows: %w", err)
+	}
+	select {
+	case res.queryID = <-queryIDChan:
+	default:
+	}
+	return res, nil
+}
+
+// readResultSet reads up to maxResultRows rows of the current result set of
+// rows. See fetch for the meaning of convert.
+func (r *queryRunner) readResultSet(rows *sqlx.Rows, convert bool) (*rawResult, error) {
+	// Column types are available even when the query returns no rows, so that
+	// an empty result can be told apart from a statement that returns no
+	// columns.
+	var err error
+	res := &rawResult{rows: [][]any{}}
+	if res.columnTypes, err = rows.ColumnTypes(); err != nil {
+		return nil, fmt.Errorf("Failed to get column types: %w", err)
+	}
+
+	// Fetch the rows, reading one more than is returned to find out whether
+	// there are more.
+	size := 0
+	for rows.Next() {
+		if len(res.rows) >= maxResultRows {
+			res.more = true
+			break
+		}
+		row, err := rows.SliceScan()
+		if err != nil {
+			return nil, fmt.Errorf("Failed to scan row: %w", err)
+		}
+		for i := range row {
+			row[i] = normalizeNull(row[i])
+		}
+		if convert {
+			for i := range row {
+				var t bool
+				if row[i], t = sanitizeCell(row[i]); t {
+					res.sanitized = true
+				}
+				// Truncated values are left as is since e.g. a truncated
+				// VARIANT is no longer valid JSON.
+				if row[i], t = truncateCell(row[i], r.opts.maxCellBytes); t {
+					res.truncated = true
+					continue
+				}
+				row[i] = r.opts.convertValue(row[i], res.columnTypes[i].DatabaseTypeName())
+			}
+			if r.opts.maxResponseBytes > 0 {
+				b, err := json.Marshal(row)
+				if err != nil {
+					return nil, fmt.Errorf("Failed to marshal row: %v", err)
+				}
+				if size += len(b); size > r.opts.maxResponseBytes {
+					res.overBudget = true
+					break
+				}
+			}
+		}
+		res.rows = append(res.rows, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("Failed to fetch rows: %w", err)
+	}
+	return res, nil
+}
+
+// uniqueColumnNames returns the names of columns, with duplicates, e.g. from
+// a join selecting a.id and b.id, made unique by appending their position.
+// Rows remain positional so nothing is lost either way, but unique names
+// keep the columns apart when they are used as keys.
+func uniqueColumnNames(columnTypes []*sql.ColumnType) []string {
+	count := map[string]int{}
+	taken := map[string]bool{}
+	for _, c := range columnTypes {
+		count[c.Name()]++
+		taken[c.Name()] = true
+	}
+	names := make([]string, len(columnTypes))
+	for i, c := range columnTypes {
+		name := c.Name()
+		if count[name] > 1 {
+			// Dedupe against existing names too, e.g. a real ID_2 column.
+			for n := i + 1; ; n++ {
+				candidate := fmt.Sprintf("%s_%d", name, n)
+				if !taken[candidate] {
+					name = candidate
+					break
+				}
+			}
+			taken[name] = true
+		}
+		names[i] = name
+	}
+	return names
+}
+
+// resultMap returns res ready to be serialized as JSON.
+func (r *queryRunner) resultMap(res *rawResult) map[string]any {
+	columnInfo := []map[string]any{}
+	names := uniqueColumnNames(res.columnTypes)
+	for i, columnType := range res.columnTypes {
+		columnInfo = append(columnInfo, map[string]any{
+			"name": names[i],
+			"type": columnType.DatabaseTypeName(),
+		})
+	}
+	result := map[string]any{
+		"column_info": columnInfo,
+		"rows":        res.rows,
+		"row_count":   len(res.rows),
+	}
+	notices := []string{}
+	if res.more {
+		notices = append(notices, fmt.Sprintf("Only first %d rows are shown", maxResultRows))
+	}
+	if res.overBudget {
+		notices = append(notices, fmt.Sprintf("Only first %d rows are shown as the response would be larger than %d bytes", len(res.rows), r.opts.maxResponseBytes))
+	}
+	if res.truncated {
+		notices = append(notices, fmt.Sprintf("Values longer than %d bytes are truncated", r.opts.maxCellBytes))
+	}
+	if res.sanitized {
+		notices = append(notices, "Invalid UTF-8 and control characters in values are replaced with U+FFFD")
+	}
+	if len(notices) > 0 {
+		result["notice"] = strings.Join(notices, ". ")
+	}
+	if res.queryID != "" {
+		result["query_id"] = res.queryID
+	}
+	return result
+}
+
+// runQuery executes query and returns its column info and up to
+// maxResultRows rows, ready to be serialized as JSON.
+func (r *queryRunner) runQuery(ctx context.Context, query string, args ...any) (result map[string]any, err error) {
+	original := query
+	query = r.limit(query)
+	start := time.Now()
+	defer func() { r.log.record(start, query, result, err) }()
+	res, err := r.fetch(ctx, true, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	for _, res := range append([]*rawResult{res}, res.next...) {
+		if err := r.checkCutOff(res); err != nil {
+			return nil, err
+		}
+	}
+	if len(res.next) > 0 {
+		// Statements returning several result sets are returned like
+		// multiple statements.
+		results := []map[string]any{r.resultMap(res)}
+		for _, next := range res.next {
+			results = append(results, r.resultMap(next))
+		}
+		result = map[string]any{"results": results}
+		if res.queryID != "" {
+			result["query_id"] = res.queryID
+		}
+		result["elapsed_ms"] = time.Since(start).Milliseconds()
+		return result, nil
+	}
+	result = r.resultMap(res)
+	if r.reportTotal && (res.more || res.overBudget) {
+		r.addTotal(ctx, result, original, args...)
+	}
+	result["elapsed_ms"] = time.Since(start).Milliseconds()
+	return result, nil
+}
+
+// addTotal adds the total number of rows of query to result, which was cut
+// off, by running it again wrapped in a COUNT(*). Only single SELECT queries
+// can be wrapped. Failing to count is logged and otherwise ignored since the
+// rows were already fetched.
+func (r *queryRunner) addTotal(ctx context.Context, result map[string]any, query string, args ...any) {
+	statements := splitStatements(query)
+	if len(statements) != 1 {
+		return
+	}
+	if kw := leadingKeyword(statements[0]); kw != "SELECT" && kw != "WITH" {
+		return
+	}
+	var total int64
+	// The query goes on its own lines so that a trailing comment doesn't
+	// swallow the closing parenthesis.
+	if err := r.queryer().GetContext(ctx, &total, fmt.Sprintf("SELECT COUNT(*) FROM (\n%s\n)", statements[0]), args...); err != nil {
+		slog.Warn("Failed to count total rows", "error", err)
+		return
+	}
+	result["total_row_count"] = total
+	notice := fmt.Sprintf("The query returns %d rows in total", total)
+	if n, ok := result["notice"].(string); ok {
+		notice = n + ". " + notice
+	}
+	result["notice"] = notice
+}
+
+// runMultiQuery executes count semicolon separated statements and returns
+// the result of each in turn. Snowflake rejects the query unless it has
+// exactly count statements, so that it runs the statements the caller checked
+// rather than however many it parses. Statements are not retried.
+func (r *queryRunner) runMultiQuery(ctx context.Context, query string, count int, args ...any) (result map[string]any, err error) {
+	if count < 1 {
+		return nil, newArgError("Query has no statements")
+	}
+	start := time.Now()
+	defer func() { r.log.record(start, query, result, err) }()
+	queryIDChan := make(chan string, 1)
+	ctx, err = gosnowflake.WithMultiStatement(gosnowflake.WithQueryIDChan(ctx, queryIDChan), count)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to enable multiple statements: %v", err)
+	}
+	rows, err := r.queryer().QueryxContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to execute query: %w", err)
+	}
+	defer rows.Close()
+
+	results := []map[string]any{}
+	for {
+		res, err := r.readResultSet(rows, true)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read result of statement %d: %w", len(results)+1, err)
+		}
+		if err := r.checkCutOff(res); err != nil {
+			return nil, fmt.Errorf("Statement %d: %w", len(results)+1, err)
+		}
+		results = append(results, r.resultMap(res))
+		if !rows.NextResultSet() {
+			break
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("Failed to execute statement %d: %w", len(results)+1, err)
+	}
+
+	result = map[string]any{
+		"results":    results,
+		"elapsed_ms": time.Since(start).Milliseconds(),
+	}
+	select {
+	case queryID := <-queryIDChan:
+		if queryID != "" {
+			result["query_id"] = queryID
+		}
+	default:
+	}
+	return result, nil
+}
+
+// runExec executes a statement that does not return a result set and
+// returns the number of affected rows.
+func (r *queryRunner) runExec(ctx context.Context, query string, args ...any) (result map[string]any, err error) {
+	start := time.Now()
+	defer func() { r.log.record(start, query, result, err) }()
+	queryIDChan := make(chan string, 1)
+	res, err := r.queryer().ExecContext(gosnowflake.WithQueryIDChan(ctx, queryIDChan), query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to execute statement: %w", err)
+	}
+
+	result = map[string]any{
+		"elapsed_ms": time.Since(start).Milliseconds(),
+	}
+	if n, err := res.RowsAffected(); err == nil {
+		result["rows_affected"] = n
+	}
+	// The driver reports -1 as Snowflake doesn't support last insert IDs.
+	if id, err := res.LastInsertId(); err == nil && id >= 0 {
+		result["last_insert_id"] = id
+	}
+	select {
+	case queryID := <-queryIDChan:
+		if queryID != "" {
+			result["query_id"] = queryID
+		}
+	default:
+	}
+	return result, nil
+}
+
+// jsonToolResult returns v encoded as indented JSON text content.
+func jsonToolResult(v any) (*mcp.CallToolResult, error) {
+	b := bytes.NewBuffer(nil)
+	jsonEnc := json.NewEncoder(b)
+	jsonEnc.SetIndent("", " ")
+	if err := jsonEnc.Encode(v); err != nil {
+		return nil, fmt.Errorf("Failed to marshal result: %v", err)
+	}
+	return mcp.NewToolResultText(b.String()), nil
+}
+
+// compactJSONToolResult is like jsonToolResult without indentation.
+func compactJSONToolResult(v any) (*mcp.CallToolResult, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to marshal result: %v", err)
+	}
+	return mcp.NewToolResultText(string(b)), nil
+}
+
+// jsonResourceContents returns v encoded as indented JSON resource contents.
+func jsonResourceContents(uri string, v any) ([]mcp.ResourceContents, error) {
+	b := bytes.NewBuffer(nil)
+	jsonEnc := json.NewEncoder(b)
+	jsonEnc.SetIndent("", " ")
+	if err := jsonEnc.Encode(v); err != nil {
+		return nil, fmt.Errorf("Failed to marshal result: %v", err)
+	}
+	return []mcp.ResourceContents{
+		mcp.TextResourceContents{
+			URI:      uri,
+			MIMEType: "application/json",
+			Text:     b.String(),
+		},
+	}, nil
+}
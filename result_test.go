@@ -0,0 +1,272 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestConvertValue(t *testing.T) {
+	tests := []struct {
+		name   string
+		v      any
+		dbType string
+		want   any
+	}{
+		{"NUMBER(38,10) string", "1234567890123456789012345678.1234567890", "FIXED", "1234567890123456789012345678.1234567890"},
+		{"NUMBER float", 0.1, "FIXED", "0.1"},
+		{"NUMBER int", int64(9007199254740993), "FIXED", "9007199254740993"},
+		{"big int", new(big.Int).Lsh(big.NewInt(1), 70), "FIXED", "1180591620717411303424"},
+		{"big float", big.NewFloat(1.5), "FIXED", "1.5"},
+		{"TIMESTAMP_TZ", time.Date(2024, 3, 1, 12, 30, 0, 500, time.FixedZone("", 5*3600+1800)), "TIMESTAMP_TZ", "2024-03-01T12:30:00.0000005+05:30"},
+		{"FLOAT", 1.25, "REAL", 1.25},
+		{"BOOLEAN", true, "BOOLEAN", true},
+		{"TEXT", "hello", "TEXT", "hello"},
+		{"VARIANT object", `{"a": [1, "x", null]}`, "VARIANT", map[string]any{"a": []any{json.Number("1"), "x", nil}}},
+		{"VARIANT string", `"x"`, "VARIANT", "x"},
+		{"VARIANT big integer", `{"id": 12345678901234567890}`, "VARIANT", map[string]any{"id": json.Number("12345678901234567890")}},
+		{"ARRAY", `[1.5, 2]`, "ARRAY", []any{json.Number("1.5"), json.Number("2")}},
+		{"OBJECT", `{}`, "OBJECT", map[string]any{}},
+		{"invalid VARIANT", `{"a":`, "VARIANT", `{"a":`},
+		{"VARIANT with trailing data", `1 2`, "VARIANT", `1 2`},
+		{"JSON in TEXT", `{"a": 1}`, "TEXT", `{"a": 1}`},
+	}
+	for _, tt := range tests {
+		got := resultOptions{}.convertValue(tt.v, tt.dbType)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: convertValue(%#v, %s) = %#v, want %#v", tt.name, tt.v, tt.dbType, got, tt.want)
+		}
+	}
+}
+
+func TestConvertValueVariantNumbersStayExact(t *testing.T) {
+	got := resultOptions{}.convertValue(`{"n": 9007199254740993}`, "VARIANT")
+	b, err := json.Marshal(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"n":9007199254740993}`; string(b) != want {
+		t.Errorf("Got %s, want %s", b, want)
+	}
+}
+
+func TestConvertValueFloatPrecision(t *testing.T) {
+	opts := resultOptions{floatPrecision: 3}
+	if got := opts.convertValue(0.1+0.2, "REAL"); got != 0.3 {
+		t.Errorf("REAL rounded to %v, want 0.3", got)
+	}
+	if got := opts.convertValue(1.23456, "FIXED"); got != "1.23456" {
+		t.Errorf("FIXED rounded to %v, want it kept exact", got)
+	}
+}
+
+func TestConvertValueTimestamps(t *testing.T) {
+	loc := time.FixedZone("", -7*3600)
+	v := time.Date(2024, 12, 31, 20, 15, 30, 123456789, loc)
+	tests := []struct {
+		dbType string
+		v      time.Time
+		want   string
+	}{
+		{"DATE", time.Date(2024, 12, 31, 0, 0, 0, 0, time.UTC), "2024-12-31"},
+		{"TIME", time.Date(1, 1, 1, 20, 15, 30, 120000000, time.UTC), "20:15:30.12"},
+		{"TIME", time.Date(1, 1, 1, 20, 15, 30, 0, time.UTC), "20:15:30"},
+		{"TIMESTAMP_NTZ", time.Date(2024, 12, 31, 20, 15, 30, 0, time.UTC), "2024-12-31T20:15:30Z"},
+		{"TIMESTAMP_LTZ", v, "2025-01-01T03:15:30.123456789Z"},
+		{"TIMESTAMP_TZ", v, "2024-12-31T20:15:30.123456789-07:00"},
+		{"TIMESTAMP_TZ", time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), "2024-06-01T00:00:00Z"},
+	}
+	for _, tt := range tests {
+		if got := (resultOptions{}).convertValue(tt.v, tt.dbType); got != tt.want {
+			t.Errorf("convertValue(%v, %s) = %v, want %s", tt.v, tt.dbType, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeNull(t *testing.T) {
+	var nilString *string
+	s := "x"
+	tests := []struct {
+		name string
+		v    any
+		want any
+	}{
+		{"nil", nil, nil},
+		{"nil bytes", []byte(nil), nil},
+		{"nil pointer", nilString, nil},
+		{"null string", sql.NullString{}, nil},
+		{"null int", sql.NullInt64{}, nil},
+		{"null float", sql.NullFloat64{}, nil},
+		{"null time", sql.NullTime{}, nil},
+		{"null string pointer", (*sql.NullString)(nil), nil},
+		{"empty bytes", []byte{}, []byte{}},
+		{"string", "", ""},
+		{"zero", int64(0), int64(0)},
+		{"pointer", &s, &s},
+		{"valid null string", sql.NullString{String: "a", Valid: true}, sql.NullString{String: "a", Valid: true}},
+	}
+	for _, tt := range tests {
+		if got := normalizeNull(tt.v); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: normalizeNull(%#v) = %#v, want %#v", tt.name, tt.v, got, tt.want)
+		}
+	}
+}
+
+func TestNullsEncodeAsJSONNull(t *testing.T) {
+	// NULLs of NUMBER, VARCHAR, TIMESTAMP and VARIANT columns as the driver
+	// may scan them.
+	row := []any{sql.NullString{}, []byte(nil), sql.NullTime{}, (*string)(nil)}
+	dbTypes := []string{"FIXED", "TEXT", "TIMESTAMP_NTZ", "VARIANT"}
+	for i := range row {
+		row[i] = resultOptions{}.convertValue(normalizeNull(row[i]), dbTypes[i])
+	}
+	b, err := json.Marshal(row)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "[null,null,null,null]"; string(b) != want {
+		t.Errorf("Got %s, want %s", b, want)
+	}
+}
+
+// columnTypes returns the column types of a query returning columns.
+func columnTypes(t *testing.T, columns ...string) []*sql.ColumnType {
+	db, _ := newFakeDB(t, func(string) fakeResult { return fakeResult{columns: columns} })
+	rows, err := db.Query("SELECT")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return types
+}
+
+func TestUniqueColumnNames(t *testing.T) {
+	tests := []struct {
+		columns []string
+		want    []string
+	}{
+		{[]string{"ID", "NAME"}, []string{"ID", "NAME"}},
+		{[]string{"ID", "NAME", "ID"}, []string{"ID_1", "NAME", "ID_3"}},
+		{[]string{"ID", "ID", "ID_2"}, []string{"ID_1", "ID_3", "ID_2"}},
+		{[]string{"A", "A", "A"}, []string{"A_1", "A_2", "A_3"}},
+		{[]string{}, []string{}},
+	}
+	for _, tt := range tests {
+		if got := uniqueColumnNames(columnTypes(t, tt.columns...)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("uniqueColumnNames(%q) = %q, want %q", tt.columns, got, tt.want)
+		}
+	}
+}
+
+func TestRunQuerySelfJoinColumns(t *testing.T) {
+	// A self-join selecting a.*, b.* returns every column twice.
+	db, _ := newFakeDB(t, func(string) fakeResult {
+		return fakeResult{
+			columns: []string{"ID", "PARENT_ID", "ID", "PARENT_ID"},
+			dbTypes: []string{"FIXED", "FIXED", "FIXED", "FIXED"},
+			rows:    [][]driver.Value{{"2", "1", "1", nil}},
+		}
+	})
+	runner := &queryRunner{db: db}
+	result, err := runner.runQuery(context.Background(), "SELECT a.*, b.* FROM t a JOIN t b ON a.parent_id = b.id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := []any{}
+	for _, c := range result["column_info"].([]map[string]any) {
+		names = append(names, c["name"])
+	}
+	if want := []any{"ID_1", "PARENT_ID_2", "ID_3", "PARENT_ID_4"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("Column names are %q, want %q", names, want)
+	}
+	if want := [][]any{{"2", "1", "1", nil}}; !reflect.DeepEqual(result["rows"], want) {
+		t.Errorf("Rows are %v, want %v", result["rows"], want)
+	}
+}
+
+// fakeNumberRows returns n rows of a single NUMBER column.
+func fakeNumberRows(n int) fakeResult {
+	r := fakeResult{columns: []string{"N"}, dbTypes: []string{"FIXED"}}
+	for i := 0; i < n; i++ {
+		r.rows = append(r.rows, []driver.Value{int64(i)})
+	}
+	return r
+}
+
+func TestRunQueryRowCount(t *testing.T) {
+	for _, n := range []int{0, 1, 5, maxResultRows, maxResultRows + 1} {
+		db, _ := newFakeDB(t, func(string) fakeResult { return fakeNumberRows(n) })
+		result, err := (&queryRunner{db: db}).runQuery(context.Background(), "SELECT n FROM t")
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := min(n, maxResultRows)
+		if result["row_count"] != want {
+			t.Errorf("row_count of %d rows is %v, want %d", n, result["row_count"], want)
+		}
+		if rows := result["rows"].([][]any); len(rows) != want {
+			t.Errorf("Got %d of %d rows, want %d", len(rows), n, want)
+		}
+	}
+}
+
+func TestRunQueryNotice(t *testing.T) {
+	tests := []struct {
+		name   string
+		rows   int
+		opts   resultOptions
+		notice string
+	}{
+		{"empty", 0, resultOptions{}, ""},
+		{"small", 3, resultOptions{}, ""},
+		{"exactly at cap", maxResultRows, resultOptions{}, ""},
+		{"over cap", maxResultRows + 1, resultOptions{}, "Only first 1000 rows are shown"},
+		{"over budget", 100, resultOptions{maxResponseBytes: 50}, "Only first 10 rows are shown as the response would be larger than 50 bytes"},
+	}
+	for _, tt := range tests {
+		db, _ := newFakeDB(t, func(string) fakeResult { return fakeNumberRows(tt.rows) })
+		result, err := (&queryRunner{db: db, opts: tt.opts}).runQuery(context.Background(), "SELECT n FROM t")
+		if err != nil {
+			t.Fatal(err)
+		}
+		notice, ok := result["notice"]
+		if tt.notice == "" && ok {
+			t.Errorf("%s: Unexpected notice %q", tt.name, notice)
+		}
+		if tt.notice != "" && notice != tt.notice {
+			t.Errorf("%s: Notice is %q, want %q", tt.name, notice, tt.notice)
+		}
+	}
+}
+
+func TestRunQueryCancel(t *testing.T) {
+	db, f := newFakeDB(t, func(string) fakeResult { return fakeResult{block: true} })
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		_, err := (&queryRunner{db: db}).runQuery(ctx, "SELECT SYSTEM$WAIT(60)")
+		done <- err
+	}()
+	for len(f.ran()) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Got error %v, want it to be cancelled", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Query kept running after cancellation")
+	}
+}
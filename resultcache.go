@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+func init() {
+	// The types making up query results, for gob to encode them behind
+	// interfaces. Semi-structured values hold numbers as json.Number.
+	gob.Register(json.Number(""))
+	gob.Register(map[string]any{})
+	gob.Register([]map[string]any{})
+	gob.Register([]any{})
+	gob.Register([][]any{})
+}
+
+// resultCache keeps pages of query results fetched by get_results in files,
+// so that paging through the same results again doesn't query Snowflake.
+// Results of a query ID never change, so entries don't expire. The least
+// recently used entries are removed to stay within the size cap. A nil
+// *resultCache disables caching.
+type resultCache struct {
+	// dir is a directory of the cache's own, removed by close.
+	dir      string
+	maxBytes int64
+
+	mu      sync.Mutex
+	entries map[string]*resultCacheEntry
+	total   int64
+}
+
+type resultCacheEntry struct {
+	size int64
+	used time.Time
+}
+
+// newResultCache returns a cache keeping up to maxBytes of results in a new
+// directory within dir.
+func newResultCache(dir string, maxBytes int64) (*resultCache, error) {
+	d, err := os.MkdirTemp(dir, "snowflake-mcp-results-")
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create result cache directory: %w", err)
+	}
+	return &resultCache{
+		dir:      d,
+		maxBytes: maxBytes,
+		entries:  map[string]*resultCacheEntry{},
+	}, nil
+}
+
+// resultCacheKey returns the key of the page of the results of queryID
+// starting at offset.
+func resultCacheKey(queryID string, offset int) string {
+	return fmt.Sprintf("%s-%d", strings.ToLower(queryID), offset)
+}
+
+func (c *resultCache) path(key string) string {
+	return filepath.Join(c.dir, key+".gob")
+}
+
+// get returns the cached result for key, if any. Failing to read it is logged
+// and treated as a miss.
+func (c *resultCache) get(key string) (map[string]any, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	var result map[string]any
+	b, err := os.ReadFile(c.path(key))
+	if err == nil {
+		err = gob.NewDecoder(bytes.NewReader(b)).Decode(&result)
+	}
+	if err != nil {
+		slog.Warn("Failed to read cached result", "key", key, "error", err)
+		c.remove(key)
+		return nil, false
+	}
+	e.used = time.Now()
+	return result, true
+}
+
+// put caches result for key, evicting the least recently used entries to make
+// room. Results larger than the whole cache or that fail to be written are
+// not cached.
+func (c *resultCache) put(key string, result map[string]any) {
+	if c == nil {
+		return
+	}
+	b := &bytes.Buffer{}
+	if err := gob.NewEncoder(b).Encode(result); err != nil {
+		slog.Warn("Failed to encode result for caching", "key", key, "error", err)
+		return
+	}
+	size := int64(b.Len())
+	if size > c.maxBytes {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.entries[key]; ok {
+		c.remove(key)
+	}
+	for c.total+size > c.maxBytes {
+		oldest := ""
+		for k, e := range c.entries {
+			if oldest == "" || e.used.Before(c.entries[oldest].used) {
+				oldest = k
+			}
+		}
+		c.remove(oldest)
+	}
+	if err := os.WriteFile(c.path(key), b.Bytes(), 0600); err != nil {
+		slog.Warn("Failed to write cached result", "key", key, "error", err)
+		os.Remove(c.path(key))
+		return
+	}
+	c.entries[key] = &resultCacheEntry{size: size, used: time.Now()}
+	c.total += size
+}
+
+// remove deletes the entry for key. c.mu must be held.
+func (c *resultCache) remove(key string) {
+	if err := os.Remove(c.path(key)); err != nil && !os.IsNotExist(err) {
+		slog.Warn("Failed to remove cached result", "key", key, "error", err)
+	}
+	c.total -= c.entries[key].size
+	delete(c.entries, key)
+}
+
+// drop removes all cached pages of the results of queryID.
+func (c *resultCache) drop(queryID string) {
+	if c == nil {
+		return
+	}
+	// Keys are made by resultCacheKey.
+	prefix := strings.ToLower(queryID) + "-"
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key := range c.entries {
+		if strings.HasPrefix(key, prefix) {
+			c.remove(key)
+		}
+	}
+}
+
+// close removes the cache directory along with all cached results.
+func (c *resultCache) close() {
+	if c == nil {
+		return
+	}
+	if err := os.RemoveAll(c.dir); err != nil {
+		slog.Warn("Failed to remove result cache directory", "dir", c.dir, "error", err)
+	}
+}
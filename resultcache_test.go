@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bytes"
+	"encoding/gob"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func cachedPage(n int) map[string]any {
+	return map[string]any{
+		"column_info": []map[string]any{{"name": "N", "type": "NUMBER"}},
+		"rows":        [][]any{{int64(n)}, {nil}, {"x"}},
+		"row_count":   3,
+	}
+}
+
+func TestResultCache(t *testing.T) {
+	b := &bytes.Buffer{}
+	if err := gob.NewEncoder(b).Encode(cachedPage(1)); err != nil {
+		t.Fatal(err)
+	}
+	// Room for two pages only.
+	c, err := newResultCache(t.TempDir(), int64(b.Len())*5/2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.close()
+
+	if _, ok := c.get(resultCacheKey("Q1", 0)); ok {
+		t.Error("Empty cache has a hit")
+	}
+	c.put(resultCacheKey("Q1", 0), cachedPage(1))
+	time.Sleep(time.Millisecond)
+	c.put(resultCacheKey("Q1", 100), cachedPage(2))
+	time.Sleep(time.Millisecond)
+	if got, ok := c.get(resultCacheKey("q1", 0)); !ok || !reflect.DeepEqual(got, cachedPage(1)) {
+		t.Errorf("get = %v, %t, want %v", got, ok, cachedPage(1))
+	}
+	time.Sleep(time.Millisecond)
+
+	// The page at offset 100 is now the least recently used one.
+	c.put(resultCacheKey("Q2", 0), cachedPage(3))
+	if _, ok := c.get(resultCacheKey("Q1", 100)); ok {
+		t.Error("Least recently used page wasn't evicted")
+	}
+	for key, want := range map[string]map[string]any{
+		resultCacheKey("Q1", 0): cachedPage(1),
+		resultCacheKey("Q2", 0): cachedPage(3),
+	} {
+		if got, ok := c.get(key); !ok || !reflect.DeepEqual(got, want) {
+			t.Errorf("get(%q) = %v, %t, want %v", key, got, ok, want)
+		}
+	}
+	if c.total > c.maxBytes {
+		t.Errorf("Cache holds %d bytes, more than %d", c.total, c.maxBytes)
+	}
+	files, err := os.ReadDir(c.dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Errorf("Cache directory has %d files, want 2", len(files))
+	}
+
+	c.close()
+	if _, err := os.Stat(c.dir); !os.IsNotExist(err) {
+		t.Errorf("Cache directory wasn't removed: %v", err)
+	}
+}
+
+func TestResultCacheSemiStructured(t *testing.T) {
+	c, err := newResultCache(t.TempDir(), 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.close()
+	// VARIANT, OBJECT and ARRAY values as converted by convertValue.
+	row := []any{}
+	for _, v := range []string{`{"a": 12345678901234567890, "b": [1.5, "x", null]}`, `[1, {"c": true}]`, `7`} {
+		parsed, err := parseJSONExact(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		row = append(row, parsed)
+	}
+	page := map[string]any{"rows": [][]any{row}}
+	c.put("k", page)
+	got, ok := c.get("k")
+	if !ok {
+		t.Fatal("Semi-structured page wasn't cached")
+	}
+	if !reflect.DeepEqual(got, page) {
+		t.Errorf("get = %#v, want %#v", got, page)
+	}
+}
+
+func TestResultCacheTooLarge(t *testing.T) {
+	c, err := newResultCache(t.TempDir(), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.close()
+	c.put("k", cachedPage(1))
+	if _, ok := c.get("k"); ok {
+		t.Error("Result larger than the cache was cached")
+	}
+}
+
+func TestResultCacheUnreadable(t *testing.T) {
+	c, err := newResultCache(t.TempDir(), 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.close()
+	c.put("k", cachedPage(1))
+	if err := os.WriteFile(c.path("k"), []byte("garbage"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := c.get("k"); ok {
+		t.Error("Corrupt entry was returned")
+	}
+	if len(c.entries) != 0 || c.total != 0 {
+		t.Errorf("Corrupt entry wasn't removed: %v, %d bytes", c.entries, c.total)
+	}
+}
+
+func TestResultCacheNil(t *testing.T) {
+	var c *resultCache
+	c.put("k", cachedPage(1))
+	if _, ok := c.get("k"); ok {
+		t.Error("Nil cache has a hit")
+	}
+	c.close()
+}
+
+func TestResultCacheDrop(t *testing.T) {
+	c, err := newResultCache(t.TempDir(), 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.close()
+	c.put(resultCacheKey("Q1", 0), cachedPage(1))
+	c.put(resultCacheKey("Q1", 1000), cachedPage(2))
+	c.put(resultCacheKey("Q10", 0), cachedPage(3))
+	c.drop("q1")
+	for key, want := range map[string]bool{
+		resultCacheKey("Q1", 0):    false,
+		resultCacheKey("Q1", 1000): false,
+		resultCacheKey("Q10", 0):   true,
+	} {
+		if _, ok := c.get(key); ok != want {
+			t.Errorf("get(%q) hit %t, want %t", key, ok, want)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/snowflakedb/gosnowflake"
+)
+
+// errCodeStatementNotFound is returned by Snowflake when the results of a
+// query can't be found, e.g. because they expired.
+const errCodeStatementNotFound = 709
+
+// queryIDPat matches Snowflake query IDs.
+var queryIDPat = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// getResults fetches the results of a previously run query with RESULT_SCAN
+// without running it again, skipping the first offset rows. Pages of results
+// are served from cache if they were fetched before. The page is recorded in
+// the cursor of queryID, which fails once too many pages were fetched.
+func getResults(ctx context.Context, runner *queryRunner, cache *resultCache, cursors *resultCursors, queryID string, offset int) (map[string]any, error) {
+	if !queryIDPat.MatchString(queryID) {
+		return nil, newArgError("Invalid query ID %q", queryID)
+	}
+	if offset < 0 {
+		return nil, newArgError("Offset must not be negative")
+	}
+	if err := cursors.fetch(queryID, offset); err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	key := resultCacheKey(queryID, offset)
+	if result, ok := cache.get(key); ok {
+		result["elapsed_ms"] = time.Since(start).Milliseconds()
+		return result, nil
+	}
+	// The query runner only reads the rows it returns, so the limit just
+	// saves Snowflake from sending the rest.
+	query := fmt.Sprintf("SELECT * FROM TABLE(RESULT_SCAN('%s')) LIMIT %d OFFSET %d", queryID, maxResultRows+1, offset)
+	result, err := runner.runQuery(ctx, query)
+	var sfErr *gosnowflake.SnowflakeError
+	if errors.As(err, &sfErr) && sfErr.Number == errCodeStatementNotFound {
+		return nil, fmt.Errorf("Results of query %s are not available. Results are only kept for 24 hours and for the user who ran the query, so run it again instead: %w", queryID, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	cache.put(key, result)
+	return result, nil
+}
+
+// closeResults closes the cursor of queryID and drops its cached pages.
+// Closing results that aren't open does nothing.
+func closeResults(cache *resultCache, cursors *resultCursors, queryID string) (bool, error) {
+	if !queryIDPat.MatchString(queryID) {
+		return false, newArgError("Invalid query ID %q", queryID)
+	}
+	cache.drop(queryID)
+	return cursors.close(queryID), nil
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"log/slog"
+	"net"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/snowflakedb/gosnowflake"
+)
+
+// retryableErrorCodes are Snowflake error codes of transient failures.
+var retryableErrorCodes = map[int]bool{
+	gosnowflake.ErrCodeServiceUnavailable: true,
+	gosnowflake.ErrFailedToGetChunk:       true,
+}
+
+// isRetryableError reports whether err is a transient failure such as a
+// network error, as opposed to e.g. a syntax or permission error.
+func isRetryableError(err error) bool {
+	if errors.Is(err, driver.ErrBadConn) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	var sfErr *gosnowflake.SnowflakeError
+	return errors.As(err, &sfErr) && retryableErrorCodes[sfErr.Number]
+}
+
+// retryPolicy controls retrying of transient failures of read-only
+// operations.
+type retryPolicy struct {
+	retries int
+	// delay is the delay before the first retry, doubling on each retry.
+	delay time.Duration
+}
+
+// do calls fn until it succeeds, fails with an error that isn't retryable or
+// the retries are exhausted.
+func (p retryPolicy) do(ctx context.Context, fn func() error) error {
+	delay := p.delay
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= p.retries || !isRetryableError(err) {
+			return err
+		}
+		slog.Warn("Retrying after transient failure", "attempt", attempt+1, "delay", delay, "error", err)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// wrap returns a resource handler that retries h on transient failures.
+func (p retryPolicy) wrap(h func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error)) func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	if p.retries <= 0 {
+		return h
+	}
+	return func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		var contents []mcp.ResourceContents
+		err := p.do(ctx, func() error {
+			var err error
+			contents, err = h(ctx, request)
+			return err
+		})
+		return contents, err
+	}
+}
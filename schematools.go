@@ -0,0 +1,291 @@
+package main
+
+import (
+	"context"
+	"sort"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// registerSchemaTools registers the tools describing the definitions of
+// objects and the privileges on them. Descriptions of schemas are cut off
+// beyond maxResponseBytes.
+func registerSchemaTools(tools *toolRegistry, db *sqlx.DB, runner *queryRunner, allowed databaseAllowlist, maxResponseBytes int) {
+	// Add a schema description tool.
+	tools.add(mcp.NewTool(
+		"describe_schema",
+		mcp.WithDescription("Describe the tables and views of a schema along with their columns in a single call. Use it to understand a schema before querying it instead of reading resources one by one."),
+		mcp.WithString("database",
+			mcp.Required(),
+			mcp.Description("Database of the schema."),
+		),
+		mcp.WithString("schema",
+			mcp.Required(),
+			mcp.Description("Schema to describe."),
+		),
+		mcp.WithBoolean("include_views",
+			mcp.Description("Whether to include views."),
+			mcp.DefaultBool(true),
+		),
+	), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		var names [2]string
+		for i, arg := range []string{"database", "schema"} {
+			v, err := stringArg(request.Params.Arguments, arg, true)
+			if err != nil {
+				return nil, err
+			}
+			if names[i], err = parseIdent(v); err != nil {
+				return nil, err
+			}
+		}
+		includeViews, err := boolArg(request.Params.Arguments, "include_views", true)
+		if err != nil {
+			return nil, err
+		}
+		if err := allowed.check(names[0]); err != nil {
+			return nil, err
+		}
+		result, err := describeSchema(ctx, db, names[0], names[1], includeViews, maxResponseBytes)
+		if err != nil {
+			return nil, err
+		}
+		return jsonToolResult(result)
+	})
+
+	// Add a table comparison tool.
+	tools.add(mcp.NewTool(
+		"compare_tables",
+		mcp.WithDescription("Compare the columns of two tables or views, e.g. the same table in two environments, and list the columns added, removed and changed in type or nullability from the left one to the right one."),
+		mcp.WithString("left_table",
+			mcp.Required(),
+			mcp.Description("Fully qualified name of the table to compare from, e.g. DEV.PUBLIC.ORDERS."),
+		),
+		mcp.WithString("right_table",
+			mcp.Required(),
+			mcp.Description("Fully qualified name of the table to compare to, e.g. PROD.PUBLIC.ORDERS."),
+		),
+	), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		var names [2]string
+		for i, arg := range []string{"left_table", "right_table"} {
+			v, err := stringArg(request.Params.Arguments, arg, true)
+			if err != nil {
+				return nil, err
+			}
+			dbName, schemaName, tableName, err := parseTableName(v)
+			if err != nil {
+				return nil, err
+			}
+			if err := allowed.check(dbName); err != nil {
+				return nil, err
+			}
+			names[i] = quoteTableName(dbName, schemaName, tableName)
+		}
+		diff, err := compareTables(ctx, db, names[0], names[1])
+		if err != nil {
+			return nil, err
+		}
+		return jsonToolResult(diff)
+	})
+
+	// Add a view lineage tool.
+	tools.add(mcp.NewTool(
+		"view_dependencies",
+		mcp.WithDescription("List the tables, views and functions a view directly reads from, to understand its lineage and which changes could break it."),
+		mcp.WithString("database",
+			mcp.Required(),
+			mcp.Description("Database of the view."),
+		),
+		mcp.WithString("schema",
+			mcp.Required(),
+			mcp.Description("Schema of the view."),
+		),
+		mcp.WithString("view",
+			mcp.Required(),
+			mcp.Description("Name of the view."),
+		),
+	), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		var names [3]string
+		for i, arg := range []string{"database", "schema", "view"} {
+			v, err := stringArg(request.Params.Arguments, arg, true)
+			if err != nil {
+				return nil, err
+			}
+			if names[i], err = parseIdent(v); err != nil {
+				return nil, err
+			}
+		}
+		if err := allowed.check(names[0]); err != nil {
+			return nil, err
+		}
+		result, err := viewDependencies(ctx, runner, names[0], names[1], names[2])
+		if err != nil {
+			return nil, err
+		}
+		return jsonToolResult(result)
+	})
+
+	// Add an INSERT template tool. It only reads the table definition, so
+	// it is allowed even in read-only mode.
+	tools.add(mcp.NewTool(
+		"generate_insert_template",
+		mcp.WithDescription("Generate a parameterized INSERT statement for a table, with a ? placeholder for each column and the types of the values to bind. Identity, sequence and computed columns are left out."),
+		mcp.WithString("database",
+			mcp.Required(),
+			mcp.Description("Database of the table."),
+		),
+		mcp.WithString("schema",
+			mcp.Required(),
+			mcp.Description("Schema of the table."),
+		),
+		mcp.WithString("table",
+			mcp.Required(),
+			mcp.Description("Name of the table."),
+		),
+	), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		var names [3]string
+		for i, arg := range []string{"database", "schema", "table"} {
+			v, err := stringArg(request.Params.Arguments, arg, true)
+			if err != nil {
+				return nil, err
+			}
+			if names[i], err = parseIdent(v); err != nil {
+				return nil, err
+			}
+		}
+		if err := allowed.check(names[0]); err != nil {
+			return nil, err
+		}
+		result, err := insertTemplate(ctx, db, names[0], names[1], names[2])
+		if err != nil {
+			return nil, err
+		}
+		return jsonToolResult(result)
+	})
+
+	// Add a DDL tool.
+	ddlTypes := []string{}
+	for t := range ddlObjectTypes {
+		ddlTypes = append(ddlTypes, t)
+	}
+	sort.Strings(ddlTypes)
+	tools.add(mcp.NewTool(
+		"get_ddl",
+		mcp.WithDescription("Get the CREATE statement of an object, including details such as clustering keys, constraints and view definitions."),
+		mcp.WithString("object_type",
+			mcp.Required(),
+			mcp.Description("Type of the object."),
+			mcp.Enum(ddlTypes...),
+		),
+		mcp.WithString("database",
+			mcp.Description("Database of the object. Required for all types except database."),
+		),
+		mcp.WithString("schema",
+			mcp.Description("Schema of the object. Required for all types except database and schema."),
+		),
+		mcp.WithString("name",
+			mcp.Required(),
+			mcp.Description("Name of the object. Functions and procedures must include their argument types, e.g. my_func(NUMBER, VARCHAR)."),
+		),
+	), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		objectType, err := enumArg(request.Params.Arguments, "object_type", "", ddlTypes...)
+		if err != nil {
+			return nil, err
+		}
+		dbName, err := stringArg(request.Params.Arguments, "database", false)
+		if err != nil {
+			return nil, err
+		}
+		schemaName, err := stringArg(request.Params.Arguments, "schema", false)
+		if err != nil {
+			return nil, err
+		}
+		name, err := stringArg(request.Params.Arguments, "name", true)
+		if err != nil {
+			return nil, err
+		}
+
+		inSchema, ok := ddlObjectTypes[objectType]
+		if !ok {
+			return nil, newArgError("Missing required argument object_type")
+		}
+		objectName, err := qualifiedObjectName(allowed, objectType, inSchema, dbName, schemaName, name)
+		if err != nil {
+			return nil, err
+		}
+
+		ddl, err := getDDL(ctx, db, objectType, objectName)
+		if err != nil {
+			return nil, err
+		}
+		return mcp.NewToolResultText(ddl), nil
+	})
+
+	// Add a grants tool.
+	grantTypes := []string{}
+	for t := range grantObjectTypes {
+		grantTypes = append(grantTypes, t)
+	}
+	sort.Strings(grantTypes)
+	tools.add(mcp.NewTool(
+		"show_grants",
+		mcp.WithDescription("List the privileges granted on an object, or to a role, to diagnose insufficient privileges errors. Give either object_type and name, or to_role."),
+		mcp.WithString("object_type",
+			mcp.Description("Type of the object."),
+			mcp.Enum(grantTypes...),
+		),
+		mcp.WithString("database",
+			mcp.Description("Database of the object. Required for all types except database and warehouse."),
+		),
+		mcp.WithString("schema",
+			mcp.Description("Schema of the object. Required for all types except database, schema and warehouse."),
+		),
+		mcp.WithString("name",
+			mcp.Description("Name of the object. Functions and procedures must include their argument types, e.g. my_func(NUMBER, VARCHAR)."),
+		),
+		mcp.WithString("to_role",
+			mcp.Description("Role to list the privileges granted to instead."),
+		),
+	), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		objectType, err := enumArg(request.Params.Arguments, "object_type", "", grantTypes...)
+		if err != nil {
+			return nil, err
+		}
+		dbName, err := stringArg(request.Params.Arguments, "database", false)
+		if err != nil {
+			return nil, err
+		}
+		schemaName, err := stringArg(request.Params.Arguments, "schema", false)
+		if err != nil {
+			return nil, err
+		}
+		name, err := stringArg(request.Params.Arguments, "name", false)
+		if err != nil {
+			return nil, err
+		}
+		role, err := stringArg(request.Params.Arguments, "to_role", false)
+		if err != nil {
+			return nil, err
+		}
+
+		var result map[string]any
+		switch {
+		case role != "" && objectType == "" && name == "":
+			result, err = showGrantsToRole(ctx, runner, role)
+		case role == "" && objectType != "" && name != "":
+			var objectName string
+			objectName, err = qualifiedObjectName(allowed, objectType, grantObjectTypes[objectType], dbName, schemaName, name)
+			if err != nil {
+				return nil, err
+			}
+			result, err = showGrantsOn(ctx, runner, objectType, objectName)
+		default:
+			return nil, newArgError("Provide either object_type and name, or to_role")
+		}
+		if err != nil {
+			return nil, err
+		}
+		return jsonToolResult(result)
+	})
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// searchObjectTypes maps the object types supported by the search_objects
+// tool to the SHOW commands listing them.
+var searchObjectTypes = map[string]string{
+	"database": "SHOW TERSE DATABASES",
+	"schema":   "SHOW TERSE SCHEMAS",
+	"table":    "SHOW TERSE TABLES",
+	"view":     "SHOW TERSE VIEWS",
+}
+
+// searchObjectOrder is the order in which object types are searched.
+var searchObjectOrder = []string{"database", "schema", "table", "view"}
+
+// foundObject is an object matched by searchObjects.
+type foundObject struct {
+	Type string `json:"type"`
+	// Name is the fully qualified, quoted name of the object.
+	Name     string `json:"name"`
+	Database string `json:"database,omitempty"`
+	Schema   string `json:"schema,omitempty"`
+}
+
+// likeLiteral returns pattern as a string literal for LIKE. Backslashes are
+// doubled so that they reach LIKE as its escape character.
+func likeLiteral(pattern string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, "'", "''").Replace(pattern) + "'"
+}
+
+// nameLikeLiteral returns a string literal for LIKE matching name. Backslashes
+// are escaped so that they match themselves. Wildcards are left alone, so
+// callers need to look for the exact name among the matches.
+func nameLikeLiteral(name string) string {
+	return likeLiteral(strings.ReplaceAll(name, `\`, `\\`))
+}
+
+// searchObjects finds objects of the given types whose names match the case
+// insensitive LIKE pattern, in dbName if given, or across the account. Objects
+// in databases that are not allowed are left out and at most limit objects are
+// returned, along with whether there were more.
+func searchObjects(ctx context.Context, db *sqlx.DB, allowed databaseAllowlist, pattern string, objectTypes []string, dbName string, limit int) ([]foundObject, bool, error) {
+	scope := "IN ACCOUNT"
+	if dbName != "" {
+		scope = "IN DATABASE " + quoteIdent(dbName)
+	}
+	found := []foundObject{}
+	for _, t := range objectTypes {
+		query := fmt.Sprintf("%s LIKE %s", searchObjectTypes[t], likeLiteral(pattern))
+		if t != "database" {
+			query += " " + scope
+		}
+		query += fmt.Sprintf(" LIMIT %d", limit+1)
+		objects := []struct {
+			Name     string `db:"name"`
+			Database string `db:"database_name"`
+			Schema   string `db:"schema_name"`
+		}{}
+		if err := db.SelectContext(ctx, &objects, query); err != nil {
+			return nil, false, fmt.Errorf("Failed to search %ss: %w", t, err)
+		}
+		for _, o := range objects {
+			obj := foundObject{Type: t, Database: o.Database, Schema: o.Schema}
+			switch t {
+			case "database":
+				if dbName != "" && o.Name != dbName {
+					continue
+				}
+				obj.Database = o.Name
+				obj.Name = quoteIdent(o.Name)
+			case "schema":
+				obj.Name = quoteIdent(o.Database) + "." + quoteIdent(o.Name)
+			default:
+				obj.Name = quoteTableName(o.Database, o.Schema, o.Name)
+			}
+			if allowed.check(obj.Database) != nil {
+				continue
+			}
+			found = append(found, obj)
+		}
+	}
+	sort.SliceStable(found, func(i, j int) bool { return found[i].Name < found[j].Name })
+	if len(found) > limit {
+		return found[:limit], true, nil
+	}
+	return found, false, nil
+}
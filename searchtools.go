@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// registerSearchTools registers the tools finding objects and columns by
+// name.
+func registerSearchTools(tools *toolRegistry, db *sqlx.DB, runner *queryRunner, allowed databaseAllowlist) {
+	// Add a column search tool.
+	tools.add(mcp.NewTool(
+		"find_columns",
+		mcp.WithDescription("Find columns whose names match a pattern across tables and views."),
+		mcp.WithString("pattern",
+			mcp.Required(),
+			mcp.Description("Case insensitive column name pattern using SQL ILIKE syntax, e.g. %customer_id%."),
+		),
+		mcp.WithString("database",
+			mcp.Description("Database to search in. If omitted, all databases are searched using SNOWFLAKE.ACCOUNT_USAGE which may lag behind recent changes."),
+		),
+		mcp.WithString("schema",
+			mcp.Description("Schema to search in. Requires database."),
+		),
+	), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		pattern, err := stringArg(request.Params.Arguments, "pattern", true)
+		if err != nil {
+			return nil, err
+		}
+		dbName, err := stringArg(request.Params.Arguments, "database", false)
+		if err != nil {
+			return nil, err
+		}
+		schemaName, err := stringArg(request.Params.Arguments, "schema", false)
+		if err != nil {
+			return nil, err
+		}
+
+		from := "SNOWFLAKE.ACCOUNT_USAGE.COLUMNS"
+		where := "COLUMN_NAME ILIKE ? AND DELETED IS NULL"
+		args := []any{pattern}
+		if dbName != "" {
+			if dbName, err = parseIdent(dbName); err != nil {
+				return nil, err
+			}
+			if err := allowed.check(dbName); err != nil {
+				return nil, err
+			}
+			from = quoteIdent(dbName) + ".INFORMATION_SCHEMA.COLUMNS"
+			where = "COLUMN_NAME ILIKE ?"
+		} else if allowed != nil {
+			names := allowed.names()
+			where += " AND TABLE_CATALOG IN (?" + strings.Repeat(", ?", len(names)-1) + ")"
+			for _, n := range names {
+				args = append(args, n)
+			}
+		}
+		if schemaName != "" {
+			if dbName == "" {
+				return nil, newArgError("Schema requires database to be specified")
+			}
+			if schemaName, err = parseIdent(schemaName); err != nil {
+				return nil, err
+			}
+			where += " AND TABLE_SCHEMA = ?"
+			args = append(args, schemaName)
+		}
+
+		result, err := runner.runQuery(ctx, fmt.Sprintf(
+			`SELECT TABLE_CATALOG, TABLE_SCHEMA, TABLE_NAME, COLUMN_NAME, DATA_TYPE FROM %s WHERE %s ORDER BY 1, 2, 3, ORDINAL_POSITION`,
+			from, where,
+		), args...)
+		if err != nil {
+			return nil, err
+		}
+		return jsonToolResult(result)
+	})
+
+	// Add an object search tool.
+	tools.add(mcp.NewTool(
+		"search_objects",
+		mcp.WithDescription("Find databases, schemas, tables and views whose names match a pattern, and get their fully qualified names. Faster than browsing resources when the name is roughly known."),
+		mcp.WithString("pattern",
+			mcp.Required(),
+			mcp.Description("Case insensitive name pattern using SQL LIKE syntax, where % matches any characters and _ any single character, e.g. %order%."),
+		),
+		mcp.WithString("object_type",
+			mcp.Description("Only search objects of this type. If omitted, all types are searched."),
+			mcp.Enum(searchObjectOrder...),
+		),
+		mcp.WithString("database",
+			mcp.Description("Only search in this database. If omitted, all databases are searched."),
+		),
+	), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		pattern, err := stringArg(request.Params.Arguments, "pattern", true)
+		if err != nil {
+			return nil, err
+		}
+		if len(pattern) > 255 {
+			return nil, newArgError("Pattern must be at most 255 characters")
+		}
+		objectType, err := enumArg(request.Params.Arguments, "object_type", "", searchObjectOrder...)
+		if err != nil {
+			return nil, err
+		}
+		objectTypes := searchObjectOrder
+		if objectType != "" {
+			objectTypes = []string{objectType}
+		}
+		dbName, err := stringArg(request.Params.Arguments, "database", false)
+		if err != nil {
+			return nil, err
+		}
+		if dbName != "" {
+			if dbName, err = parseIdent(dbName); err != nil {
+				return nil, err
+			}
+			if err := allowed.check(dbName); err != nil {
+				return nil, err
+			}
+		}
+		objects, more, err := searchObjects(ctx, db, allowed, pattern, objectTypes, dbName, maxResultRows)
+		if err != nil {
+			return nil, err
+		}
+		result := map[string]any{"objects": objects}
+		if more {
+			result["notice"] = fmt.Sprintf("Only the first %d objects are returned, use a more specific pattern.", maxResultRows)
+		}
+		return jsonToolResult(result)
+	})
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// readSecretFile reads a secret such as a password or token from a file,
+// e.g. a mounted container secret, trimming surrounding whitespace.
+func readSecretFile(path string) (string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("Failed to read secret file: %w", err)
+	}
+	secret := strings.TrimSpace(string(b))
+	if secret == "" {
+		return "", fmt.Errorf("Secret file %s is empty", path)
+	}
+	return secret, nil
+}
+
+// loadSecretFile sets *secret from the file at path, if given. The secret
+// must not also be given through flagName.
+func loadSecretFile(secret *string, path, flagName string) error {
+	if path == "" {
+		return nil
+	}
+	if *secret != "" {
+		return fmt.Errorf("Please provide either -%s or -%s-file, not both", flagName, flagName)
+	}
+	s, err := readSecretFile(path)
+	if err != nil {
+		return err
+	}
+	*secret = s
+	return nil
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// selfTestCheck is the outcome of one self test check.
+type selfTestCheck struct {
+	Name string `json:"name"`
+	// Capability is what the check tells the agent is able to do.
+	Capability string `json:"capability"`
+	OK         bool   `json:"ok"`
+	Detail     string `json:"detail,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// selfTestReport is the capability report of the self test.
+type selfTestReport struct {
+	Role      string          `json:"role"`
+	Warehouse *string         `json:"warehouse"`
+	Checks    []selfTestCheck `json:"checks"`
+}
+
+// selfTest runs harmless statements to find out what the agent can do with the
+// grants of the current role, so that missing privileges can be diagnosed
+// before tool calls start failing.
+func selfTest(ctx context.Context, db *sqlx.DB) selfTestReport {
+	report := selfTestReport{}
+	add := func(name, capability string, detail string, err error) {
+		check := selfTestCheck{Name: name, Capability: capability, OK: err == nil, Detail: detail}
+		if err != nil {
+			check.Error = err.Error()
+		}
+		report.Checks = append(report.Checks, check)
+	}
+
+	var one int
+	err := db.GetContext(ctx, &one, "SELECT 1")
+	add("connect", "Run statements in a Snowflake session", "", err)
+	if err != nil {
+		return report
+	}
+
+	session := struct {
+		Role      string  `db:"ROLE"`
+		Warehouse *string `db:"WAREHOUSE"`
+	}{}
+	err = db.GetContext(ctx, &session, `SELECT CURRENT_ROLE() AS "ROLE", CURRENT_WAREHOUSE() AS "WAREHOUSE"`)
+	add("session", "Read the current role and warehouse", "", err)
+	report.Role, report.Warehouse = session.Role, session.Warehouse
+
+	databases := []struct {
+		Name string `db:"name"`
+	}{}
+	err = db.SelectContext(ctx, &databases, "SHOW TERSE DATABASES")
+	detail := ""
+	if err == nil {
+		detail = fmt.Sprintf("%d databases visible", len(databases))
+		if len(databases) == 0 {
+			err = fmt.Errorf("The role can't see any database, grant it USAGE on the databases to expose")
+		}
+	}
+	add("list_databases", "Browse databases, schemas and tables", detail, err)
+
+	detail, err = checkWarehouse(ctx, db, session.Warehouse)
+	add("warehouse", "Run queries that scan tables", detail, err)
+
+	_, err = db.ExecContext(ctx, "SELECT 1 FROM SNOWFLAKE.ACCOUNT_USAGE.QUERY_HISTORY LIMIT 0")
+	if err != nil {
+		err = fmt.Errorf("The role can't read SNOWFLAKE.ACCOUNT_USAGE, query_history falls back to INFORMATION_SCHEMA which covers the last 7 days: %w", err)
+	}
+	add("account_usage", "Read query history and usage from SNOWFLAKE.ACCOUNT_USAGE", "", err)
+
+	return report
+}
+
+// checkWarehouse checks that the session has a warehouse it can use, either
+// running or set to resume automatically.
+func checkWarehouse(ctx context.Context, db *sqlx.DB, name *string) (string, error) {
+	if name == nil {
+		return "", fmt.Errorf("No warehouse is in use, set -warehouse and make sure the role has USAGE on it")
+	}
+	warehouses := []struct {
+		Name       string `db:"name"`
+		State      string `db:"state"`
+		AutoResume string `db:"auto_resume"`
+	}{}
+	if err := db.SelectContext(ctx, &warehouses, "SHOW WAREHOUSES LIKE "+nameLikeLiteral(*name)); err != nil {
+		return "", fmt.Errorf("Failed to show warehouse %s: %w", *name, err)
+	}
+	for _, w := range warehouses {
+		if w.Name != *name {
+			continue
+		}
+		detail := fmt.Sprintf("%s is %s", w.Name, strings.ToLower(w.State))
+		if w.State != "STARTED" && w.AutoResume != "true" {
+			return detail, fmt.Errorf("Warehouse %s is %s and doesn't resume automatically, resume it or use -auto-resume", w.Name, strings.ToLower(w.State))
+		}
+		return detail, nil
+	}
+	return "", fmt.Errorf("Warehouse %s is not visible to the role, grant it USAGE on the warehouse", *name)
+}
+
+// logSelfTest logs the outcome of each check of report.
+func logSelfTest(report selfTestReport) {
+	for _, c := range report.Checks {
+		if c.OK {
+			slog.Info("Self test passed", "check", c.Name, "detail", c.Detail)
+		} else {
+			slog.Warn("Self test failed", "check", c.Name, "capability", c.Capability, "error", c.Error)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// sessionParamPat matches the names of session parameters.
+var sessionParamPat = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// parseSessionParams parses session parameters given as KEY=VALUE, keyed by
+// the upper case parameter name.
+func parseSessionParams(params []string) (map[string]string, error) {
+	ret := map[string]string{}
+	for _, p := range params {
+		k, v, ok := strings.Cut(p, "=")
+		k = strings.TrimSpace(k)
+		if !ok || !sessionParamPat.MatchString(k) {
+			return nil, fmt.Errorf("Session parameter %q must be given as KEY=VALUE", p)
+		}
+		ret[strings.ToUpper(k)] = strings.TrimSpace(v)
+	}
+	return ret, nil
+}
+
+// sessionParamLiteral returns v as a SQL literal for ALTER SESSION. Numbers
+// and booleans are left as is and everything else is quoted as a string.
+func sessionParamLiteral(v string) string {
+	if _, err := strconv.ParseFloat(v, 64); err == nil {
+		return v
+	}
+	if strings.EqualFold(v, "true") || strings.EqualFold(v, "false") {
+		return v
+	}
+	return "'" + strings.ReplaceAll(v, "'", "''") + "'"
+}
+
+// checkSessionParams sets params on one session with ALTER SESSION so that
+// Snowflake reports any invalid ones.
+func checkSessionParams(ctx context.Context, db *sqlx.DB, params map[string]string) error {
+	for k, v := range params {
+		if _, err := db.ExecContext(ctx, fmt.Sprintf("ALTER SESSION SET %s = %s", k, sessionParamLiteral(v))); err != nil {
+			return fmt.Errorf("Invalid session parameter %s: %w", k, err)
+		}
+	}
+	return nil
+}
+
+// Session isolation modes, telling whether tool calls running queries given
+// by the agent share the connection pool or get a session of their own.
+const (
+	sessionIsolationShared     = "shared"
+	sessionIsolationPerRequest = "per-request"
+)
+
+// isolated returns a copy of r running queries on a dedicated connection,
+// and a function to call once done, which discards the connection so that
+// session state changed by the queries, e.g. with USE, doesn't carry over to
+// later calls. Connections from the pool never had such changes made, so
+// they start out with the role and warehouse set on login.
+func (r *queryRunner) isolated(ctx context.Context) (*queryRunner, func(), error) {
+	conn, err := r.db.Connx(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Failed to get a connection: %w", err)
+	}
+	c := *r
+	c.conn = conn
+	return &c, func() { discardConn(conn) }, nil
+}
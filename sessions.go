@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// sessionTracker is a connector recording the IDs of the Snowflake sessions
+// it opens, so that the server's own sessions can be told apart from others.
+// A nil *sessionTracker tracks no sessions.
+type sessionTracker struct {
+	driver.Connector
+
+	mu  sync.Mutex
+	ids map[int64]bool
+}
+
+func newSessionTracker(c driver.Connector) *sessionTracker {
+	return &sessionTracker{Connector: c, ids: map[int64]bool{}}
+}
+
+// Connect opens a connection and records the ID of its session. Connections
+// whose session ID can't be determined are closed and fail, so that no
+// session of the server goes untracked.
+func (t *sessionTracker) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := t.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	id, err := connSessionID(ctx, conn)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("Failed to get session ID: %w", err)
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.ids[id] = true
+	return conn, nil
+}
+
+// connSessionID returns the ID of the session of conn.
+func connSessionID(ctx context.Context, conn driver.Conn) (int64, error) {
+	q, ok := conn.(driver.QueryerContext)
+	if !ok {
+		return 0, fmt.Errorf("Connection doesn't support queries")
+	}
+	rows, err := q.QueryContext(ctx, "SELECT CURRENT_SESSION()", nil)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	dest := make([]driver.Value, 1)
+	if err := rows.Next(dest); err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(fmt.Sprint(dest[0]), 10, 64)
+}
+
+// owns reports whether the session with the given ID was opened by t.
+func (t *sessionTracker) owns(id int64) bool {
+	if t == nil {
+		return false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.ids[id]
+}
+
+// sessionIDs returns the IDs of the sessions opened by t in order, as strings
+// since session IDs may be too large for JSON numbers.
+func (t *sessionTracker) sessionIDs() []string {
+	if t == nil {
+		return []string{}
+	}
+	t.mu.Lock()
+	ids := make([]int64, 0, len(t.ids))
+	for id := range t.ids {
+		ids = append(ids, id)
+	}
+	t.mu.Unlock()
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	ret := make([]string, len(ids))
+	for i, id := range ids {
+		ret[i] = strconv.FormatInt(id, 10)
+	}
+	return ret
+}
+
+// showSessions returns up to limit of the most recent sessions of userName,
+// or of the current user if empty, created within the last day, along with
+// the IDs of the sessions opened by own. It uses
+// SNOWFLAKE.ACCOUNT_USAGE.SESSIONS, which may lag behind by up to 3 hours.
+func showSessions(ctx context.Context, runner *queryRunner, own *sessionTracker, userName string, limit int) (map[string]any, error) {
+	user := "CURRENT_USER()"
+	args := []any{}
+	if userName != "" {
+		user = "?"
+		args = append(args, userName)
+	}
+	result, err := runner.runQuery(ctx, fmt.Sprintf(
+		`SELECT SESSION_ID, USER_NAME, CREATED_ON, AUTHENTICATION_METHOD, CLIENT_APPLICATION_ID, CLIENT_ENVIRONMENT
+FROM SNOWFLAKE.ACCOUNT_USAGE.SESSIONS
+WHERE USER_NAME = %s AND CREATED_ON >= DATEADD(DAY, -1, CURRENT_TIMESTAMP())
+ORDER BY CREATED_ON DESC LIMIT %d`,
+		user, limit,
+	), args...)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to list sessions. The role needs access to SNOWFLAKE.ACCOUNT_USAGE, e.g. through the GOVERNANCE_VIEWER database role: %w", err)
+	}
+	result["server_session_ids"] = own.sessionIDs()
+	result["source"] = "SNOWFLAKE.ACCOUNT_USAGE.SESSIONS, which may lag behind by up to 3 hours"
+	return result, nil
+}
+
+// abortSession aborts the session with the given ID along with its running
+// queries. Sessions opened by own are refused.
+func abortSession(ctx context.Context, runner *queryRunner, own *sessionTracker, sessionID int64) (string, error) {
+	if own.owns(sessionID) {
+		return "", newArgError("Session %d is one of the server's own sessions and can't be aborted", sessionID)
+	}
+	var status string
+	if err := runner.db.GetContext(ctx, &status, "SELECT SYSTEM$ABORT_SESSION(?)", sessionID); err != nil {
+		return "", fmt.Errorf("Failed to abort session %d. Only the user of the session or an account administrator can abort it: %w", sessionID, err)
+	}
+	return status, nil
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/snowflakedb/gosnowflake"
+)
+
+// registerSessionTools registers the tools reporting on the session, the
+// role and the server, and managing sessions. own tracks the sessions of the
+// server, which can't be aborted.
+func registerSessionTools(tools *toolRegistry, db *sqlx.DB, runner *queryRunner, own *sessionTracker, cursors *resultCursors) {
+	// Add a version info tool.
+	tools.add(mcp.NewTool(
+		"version_info",
+		mcp.WithDescription("Get the versions of snowflake-mcp, the Snowflake Go driver and the Snowflake server."),
+	), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		var serverVersion string
+		if err := db.GetContext(ctx, &serverVersion, "SELECT CURRENT_VERSION()"); err != nil {
+			return nil, fmt.Errorf("Failed to get server version: %w", err)
+		}
+
+		return jsonToolResult(map[string]any{
+			"snowflake_mcp_version": version,
+			"driver_version":        gosnowflake.SnowflakeGoDriverVersion,
+			"server_version":        serverVersion,
+		})
+	})
+
+	// Add a query history tool.
+	tools.add(mcp.NewTool(
+		"query_history",
+		mcp.WithDescription("Get recent queries of the current user with their status, elapsed time and bytes scanned."),
+		mcp.WithNumber("limit",
+			mcp.Description(fmt.Sprintf("Number of queries to return, at most %d.", maxResultRows)),
+			mcp.DefaultNumber(20),
+		),
+		mcp.WithString("start_time",
+			mcp.Description("Only include queries started at or after this time, e.g. 2024-01-31T09:00:00Z."),
+		),
+		mcp.WithString("end_time",
+			mcp.Description("Only include queries started at or before this time."),
+		),
+	), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		limit, err := intArg(request.Params.Arguments, "limit", 20)
+		if err != nil {
+			return nil, err
+		}
+		if limit < 1 || limit > maxResultRows {
+			return nil, newArgError("Limit must be between 1 and %d", maxResultRows)
+		}
+		startTime, err := stringArg(request.Params.Arguments, "start_time", false)
+		if err != nil {
+			return nil, err
+		}
+		endTime, err := stringArg(request.Params.Arguments, "end_time", false)
+		if err != nil {
+			return nil, err
+		}
+		result, err := queryHistory(ctx, runner, limit, startTime, endTime)
+		if err != nil {
+			return nil, err
+		}
+		return jsonToolResult(result)
+	})
+
+	// Add session tools. Aborting sessions is disabled in read-only mode.
+	tools.add(mcp.NewTool(
+		"show_sessions",
+		mcp.WithDescription("List the sessions of a user created in the last day, to find runaway sessions left behind, e.g. by a misbehaving agent. Also returns the IDs of the sessions of this server, which can't be aborted."),
+		mcp.WithString("user",
+			mcp.Description("User whose sessions to list. Defaults to the current user."),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description(fmt.Sprintf("Number of sessions to return, at most %d.", maxResultRows)),
+			mcp.DefaultNumber(20),
+		),
+	), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		user, err := stringArg(request.Params.Arguments, "user", false)
+		if err != nil {
+			return nil, err
+		}
+		if user != "" {
+			if user, err = parseIdent(user); err != nil {
+				return nil, err
+			}
+		}
+		limit, err := intArg(request.Params.Arguments, "limit", 20)
+		if err != nil {
+			return nil, err
+		}
+		if limit < 1 || limit > maxResultRows {
+			return nil, newArgError("Limit must be between 1 and %d", maxResultRows)
+		}
+		result, err := showSessions(ctx, runner, own, user, limit)
+		if err != nil {
+			return nil, err
+		}
+		return jsonToolResult(result)
+	})
+
+	tools.add(mcp.NewTool(
+		"abort_session",
+		mcp.WithDescription("Abort a session and the queries running in it, e.g. a runaway session found with show_sessions. The sessions of this server can't be aborted."),
+		mcp.WithString("session_id",
+			mcp.Required(),
+			mcp.Description("ID of the session to abort, as a string since session IDs may be too large for JSON numbers."),
+		),
+	), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		id, err := stringArg(request.Params.Arguments, "session_id", true)
+		if err != nil {
+			return nil, err
+		}
+		sessionID, err := strconv.ParseInt(id, 10, 64)
+		if err != nil || sessionID <= 0 {
+			return nil, newArgError("Invalid session ID %q", id)
+		}
+		status, err := abortSession(ctx, runner, own, sessionID)
+		if err != nil {
+			return nil, err
+		}
+		return mcp.NewToolResultText(status), nil
+	})
+
+	// Add a session context tool.
+	tools.add(mcp.NewTool(
+		"whoami",
+		mcp.WithDescription("Get the current account, user, role, warehouse, database and schema of the session, and the number of results open for paging with get_results. Useful to debug object not found errors."),
+	), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Warehouse, database and schema are NULL when not set.
+		session := struct {
+			Account   string  `db:"ACCOUNT" json:"account"`
+			User      string  `db:"USER" json:"user"`
+			Role      string  `db:"ROLE" json:"role"`
+			Warehouse *string `db:"WAREHOUSE" json:"warehouse"`
+			Database  *string `db:"DATABASE" json:"database"`
+			Schema    *string `db:"SCHEMA" json:"schema"`
+
+			OpenResults int `db:"-" json:"open_results"`
+		}{}
+		if err := db.GetContext(ctx, &session, `SELECT CURRENT_ACCOUNT() AS "ACCOUNT", CURRENT_USER() AS "USER", CURRENT_ROLE() AS "ROLE", CURRENT_WAREHOUSE() AS "WAREHOUSE", CURRENT_DATABASE() AS "DATABASE", CURRENT_SCHEMA() AS "SCHEMA"`); err != nil {
+			return nil, fmt.Errorf("Failed to get session context: %w", err)
+		}
+		session.OpenResults = cursors.count()
+		return jsonToolResult(session)
+	})
+
+	// Add a self test tool.
+	tools.add(mcp.NewTool(
+		"self_test",
+		mcp.WithDescription("Check what the current role is able to do, e.g. list databases, use the warehouse and read account usage, and report each capability with the error if it is missing. Use it to diagnose insufficient privileges."),
+	), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return jsonToolResult(selfTest(ctx, db))
+	})
+}
@@ -0,0 +1,319 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// sqlKeywords are the words whose case formatSQL normalizes. Other words are
+// left as written.
+var sqlKeywords = wordSet(`ALL ALTER AND ANY AS ASC BETWEEN BY CASE CAST CREATE CROSS DELETE DESC
+DISTINCT DROP ELSE END EXCEPT EXISTS FALSE FETCH FIRST FOR FROM FULL GROUP HAVING IF ILIKE IN INNER
+INSERT INTERSECT INTO IS JOIN LATERAL LEFT LIKE LIMIT MATCHED MERGE MINUS NATURAL NEXT NOT NULL NULLS
+OFFSET ON ONLY OR ORDER OUTER OVER PARTITION PIVOT QUALIFY RECURSIVE REPLACE RIGHT RLIKE ROW ROWS
+SAMPLE SELECT SET SOME TABLE TABLESAMPLE THEN TOP TRUE TRY_CAST UNION UNPIVOT UPDATE USING VALUES
+VIEW WHEN WHERE WITH WITHIN`)
+
+// sqlClauseWords are the keywords starting a clause on a new line.
+var sqlClauseWords = wordSet(`SELECT FROM WHERE GROUP ORDER HAVING QUALIFY LIMIT OFFSET FETCH UNION
+INTERSECT EXCEPT MINUS WITH JOIN INNER LEFT RIGHT FULL CROSS NATURAL SET VALUES INSERT UPDATE DELETE
+MERGE`)
+
+// sqlJoinWords are the keywords that may precede JOIN in the same clause.
+var sqlJoinWords = wordSet(`INNER LEFT RIGHT FULL CROSS NATURAL OUTER`)
+
+// sqlFuncKeywords are keywords that are also functions, so that they are not
+// followed by a space before an opening parenthesis.
+var sqlFuncKeywords = wordSet(`CAST IF LEFT REPLACE RIGHT TRY_CAST`)
+
+// sqlOperators are the operators longer than a character, longest first.
+var sqlOperators = []string{"->>", "::", "<=", ">=", "<>", "!=", "||", "=>", "->"}
+
+func wordSet(words string) map[string]bool {
+	set := map[string]bool{}
+	for _, w := range strings.Fields(words) {
+		set[w] = true
+	}
+	return set
+}
+
+type sqlTokenKind int
+
+const (
+	tokWord sqlTokenKind = iota
+	// tokQuoted is a quoted identifier.
+	tokQuoted
+	tokString
+	tokComment
+	tokLineComment
+	tokPunct
+	tokOperator
+)
+
+type sqlToken struct {
+	kind sqlTokenKind
+	text string
+}
+
+// tokenizeSQL splits sql into tokens, dropping whitespace.
+func tokenizeSQL(sql string) ([]sqlToken, error) {
+	tokens := []sqlToken{}
+	for i := 0; i < len(sql); {
+		c := sql[i]
+		start := i
+		var kind sqlTokenKind
+		switch {
+		case strings.IndexByte(" \t\n\r\f\v", c) >= 0:
+			i++
+			continue
+		case c == '\'' || c == '"':
+			j := i + 1
+			for j < len(sql) {
+				if sql[j] == '\\' && c == '\'' {
+					j += 2
+					continue
+				}
+				if sql[j] == c {
+					if j+1 < len(sql) && sql[j+1] == c {
+						j += 2
+						continue
+					}
+					break
+				}
+				j++
+			}
+			if j >= len(sql) {
+				return nil, fmt.Errorf("Unterminated literal or quoted identifier")
+			}
+			i, kind = j+1, tokString
+			if c == '"' {
+				kind = tokQuoted
+			}
+		case strings.HasPrefix(sql[i:], "$$"):
+			j := strings.Index(sql[i+2:], "$$")
+			if j < 0 {
+				return nil, fmt.Errorf("Unterminated $$ literal")
+			}
+			i, kind = i+j+4, tokString
+		case strings.HasPrefix(sql[i:], "--"), strings.HasPrefix(sql[i:], "//"):
+			j := strings.IndexByte(sql[i:], '\n')
+			if j < 0 {
+				j = len(sql) - i
+			}
+			i, kind = i+j, tokLineComment
+		case strings.HasPrefix(sql[i:], "/*"):
+			j := strings.Index(sql[i+2:], "*/")
+			if j < 0 {
+				return nil, fmt.Errorf("Unterminated comment")
+			}
+			i, kind = i+j+4, tokComment
+		case c >= '0' && c <= '9':
+			// Numbers may have a fraction and a signed exponent.
+			j := i + 1
+			for j < len(sql) && (isIdentByte(sql[j]) || sql[j] == '.' ||
+				(sql[j] == '+' || sql[j] == '-') && (sql[j-1] == 'e' || sql[j-1] == 'E')) {
+				j++
+			}
+			i, kind = j, tokWord
+		case c == ':' && i+1 < len(sql) && isIdentByte(sql[i+1]), isIdentByte(c), c == '?':
+			// Words include :name placeholders and :path accesses.
+			j := i + 1
+			for c != '?' && j < len(sql) && isIdentByte(sql[j]) {
+				j++
+			}
+			i, kind = j, tokWord
+		case strings.IndexByte("(),;.", c) >= 0:
+			i, kind = i+1, tokPunct
+		default:
+			i, kind = i+1, tokOperator
+			for _, op := range sqlOperators {
+				if strings.HasPrefix(sql[start:], op) {
+					i = start + len(op)
+					break
+				}
+			}
+		}
+		tokens = append(tokens, sqlToken{kind: kind, text: strings.TrimRight(sql[start:i], " \t\r")})
+	}
+	return tokens, nil
+}
+
+// sqlLevel is a level of parentheses while formatting.
+type sqlLevel struct {
+	// subquery is whether the parentheses hold a query, whose clauses go on
+	// their own lines. The top level is a subquery too.
+	subquery bool
+	// base is the indentation of the clauses of the level.
+	base int
+	// clause is the keyword of the current clause.
+	clause string
+	// between is whether the next AND belongs to a BETWEEN.
+	between bool
+}
+
+// sqlFormatter builds up the formatted SQL.
+type sqlFormatter struct {
+	out         []byte
+	lineIndent  int
+	atLineStart bool
+	// prev is the previously written token, if any.
+	prev *sqlToken
+	// prevKeyword and prevUnary tell whether prev is a keyword and a unary
+	// operator.
+	prevKeyword, prevUnary bool
+	// selectList is whether the first item of a SELECT list is yet to come.
+	selectList bool
+}
+
+// newline starts a new line indented by indent, or changes the indentation
+// if the current line is still empty.
+func (f *sqlFormatter) newline(indent int) {
+	if f.atLineStart {
+		f.out = f.out[:bytes.LastIndexByte(f.out, '\n')+1]
+	} else {
+		f.out = append(bytes.TrimRight(f.out, " "), '\n')
+	}
+	f.out = append(f.out, strings.Repeat("  ", indent)...)
+	f.lineIndent = indent
+	f.atLineStart = true
+}
+
+// spaceBefore reports whether t is separated from the previous token by a
+// space.
+func (f *sqlFormatter) spaceBefore(t sqlToken) bool {
+	p := f.prev
+	switch {
+	case p == nil || f.atLineStart || f.prevUnary:
+		return false
+	case p.text == "(" || p.text == "." || p.text == "::" || p.text == "@" || p.text == "[":
+		return false
+	case t.text == ")" || t.text == "," || t.text == ";" || t.text == "." || t.text == "::" || t.text == "]":
+		return false
+	case t.text == "(" || t.text == "[":
+		// Function calls and element accesses.
+		return p.kind == tokWord && f.prevKeyword && !sqlFuncKeywords[strings.ToUpper(p.text)] ||
+			p.kind != tokWord && p.kind != tokQuoted && p.text != ")" && p.text != "]"
+	case t.kind == tokWord && strings.HasPrefix(t.text, ":"):
+		// Path accesses follow what they access, unlike placeholders.
+		return p.kind != tokWord && p.kind != tokQuoted && p.text != ")" && p.text != "]"
+	}
+	return true
+}
+
+func (f *sqlFormatter) write(t sqlToken, keyword, unary bool) {
+	if f.spaceBefore(t) {
+		f.out = append(f.out, ' ')
+	}
+	f.out = append(f.out, t.text...)
+	f.atLineStart = false
+	f.prev, f.prevKeyword, f.prevUnary = &t, keyword, unary
+}
+
+// formatSQL returns sql with the case of keywords normalized to upper or
+// lower case, clauses of queries on their own lines, the items of SELECT
+// lists and the conditions of WHERE, HAVING and QUALIFY clauses on their own
+// indented lines and subqueries indented. Literals, quoted identifiers and
+// comments are kept as is.
+func formatSQL(sql string, upper bool) (string, error) {
+	tokens, err := tokenizeSQL(sql)
+	if err != nil {
+		return "", newArgError("Failed to parse SQL: %v", err)
+	}
+	f := &sqlFormatter{atLineStart: true}
+	levels := []sqlLevel{{subquery: true}}
+	for i, t := range tokens {
+		var next sqlToken
+		if i+1 < len(tokens) {
+			next = tokens[i+1]
+		}
+		level := &levels[len(levels)-1]
+		upperText := strings.ToUpper(t.text)
+		keyword := t.kind == tokWord && sqlKeywords[upperText] && (f.prev == nil || f.prev.text != ".")
+		if keyword {
+			t.text = strings.ToLower(t.text)
+			if upper {
+				t.text = upperText
+			}
+		}
+
+		if f.selectList && !(keyword && (upperText == "DISTINCT" || upperText == "ALL")) {
+			f.selectList = false
+			if t.kind != tokLineComment {
+				f.newline(level.base + 1)
+			}
+		}
+
+		switch {
+		case t.kind == tokLineComment:
+			f.write(t, false, false)
+			f.newline(f.lineIndent)
+			continue
+		case t.text == "(":
+			f.write(t, false, false)
+			nextUpper := strings.ToUpper(next.text)
+			levels = append(levels, sqlLevel{
+				subquery: next.kind == tokWord && (nextUpper == "SELECT" || nextUpper == "WITH"),
+				base:     f.lineIndent + 1,
+			})
+			continue
+		case t.text == ")":
+			if len(levels) > 1 {
+				if level.subquery {
+					f.newline(level.base - 1)
+				}
+				levels = levels[:len(levels)-1]
+			}
+			f.write(t, false, false)
+			continue
+		case t.text == ";":
+			f.write(t, false, false)
+			levels = []sqlLevel{{subquery: true}}
+			if i+1 < len(tokens) {
+				// A blank line separates statements.
+				f.out = append(f.out, '\n')
+				f.newline(0)
+			}
+			continue
+		case t.text == "," && level.subquery && (level.clause == "SELECT" || level.clause == "WITH"):
+			f.write(t, false, false)
+			if level.clause == "SELECT" {
+				f.newline(level.base + 1)
+			} else {
+				f.newline(level.base)
+			}
+			continue
+		}
+
+		if keyword && level.subquery {
+			prevUpper := ""
+			if f.prev != nil && f.prevKeyword {
+				prevUpper = strings.ToUpper(f.prev.text)
+			}
+			switch {
+			case upperText == "BETWEEN":
+				level.between = true
+			case upperText == "AND" && level.between:
+				level.between = false
+			case upperText == "AND" || upperText == "OR":
+				if level.clause == "WHERE" || level.clause == "HAVING" || level.clause == "QUALIFY" {
+					f.newline(level.base + 1)
+				}
+			case !sqlClauseWords[upperText],
+				upperText == "JOIN" && sqlJoinWords[prevUpper],
+				upperText == "GROUP" && prevUpper == "WITHIN",
+				(upperText == "LEFT" || upperText == "RIGHT") && next.text == "(":
+			default:
+				f.newline(level.base)
+				level.clause = upperText
+				level.between = false
+				f.selectList = upperText == "SELECT"
+			}
+		}
+
+		unary := t.kind == tokOperator && (t.text == "-" || t.text == "+") &&
+			(f.prev == nil || f.prevKeyword || f.prev.kind == tokOperator || f.prev.text == "(" || f.prev.text == ",")
+		f.write(t, keyword, unary)
+	}
+	return strings.TrimSpace(string(f.out)), nil
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// stageInfo is the metadata of a stage as reported by SHOW STAGES.
+type stageInfo struct {
+	Name    string `db:"name" json:"name"`
+	Type    string `db:"type" json:"type"`
+	URL     string `db:"url" json:"url,omitempty"`
+	Comment string `db:"comment" json:"comment,omitempty"`
+}
+
+// showStage returns the metadata of a stage.
+func showStage(ctx context.Context, db *sqlx.DB, dbName, schemaName, stageName string) (stageInfo, error) {
+	stages := []stageInfo{}
+	query := fmt.Sprintf("SHOW STAGES LIKE %s IN SCHEMA %s.%s", nameLikeLiteral(stageName), sqlIdent(dbName), sqlIdent(schemaName))
+	if err := db.SelectContext(ctx, &stages, query); err != nil {
+		return stageInfo{}, fmt.Errorf("Failed to get stage %s: %w", stageName, err)
+	}
+	for _, s := range stages {
+		if s.Name == stageName {
+			return s, nil
+		}
+	}
+	for _, s := range stages {
+		if strings.EqualFold(s.Name, stageName) {
+			return s, nil
+		}
+	}
+	return stageInfo{}, fmt.Errorf("Stage %s not found", stageName)
+}
+
+// stageFile is a file in a stage as reported by LIST.
+type stageFile struct {
+	Name         string `db:"name" json:"name"`
+	Size         int64  `db:"size" json:"size"`
+	LastModified string `db:"last_modified" json:"last_modified"`
+}
+
+// listStageFiles returns up to maxResultRows files in a stage, and whether
+// there are more. External stages are listed through their storage
+// integration or credentials, so listing them may fail where internal stages
+// don't.
+func listStageFiles(ctx context.Context, db *sqlx.DB, dbName, schemaName, stageName string) ([]stageFile, bool, error) {
+	rows, err := db.QueryxContext(ctx, fmt.Sprintf("LIST @%s.%s.%s", sqlIdent(dbName), sqlIdent(schemaName), sqlIdent(stageName)))
+	if err != nil {
+		return nil, false, fmt.Errorf("Failed to list stage %s: %w", stageName, err)
+	}
+	defer rows.Close()
+
+	files := []stageFile{}
+	for rows.Next() {
+		if len(files) >= maxResultRows {
+			return files, true, nil
+		}
+		f := stageFile{}
+		if err := rows.StructScan(&f); err != nil {
+			return nil, false, fmt.Errorf("Failed to scan rows: %v", err)
+		}
+		files = append(files, f)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, false, fmt.Errorf("Failed to list stage files: %w", err)
+	}
+	return files, false, nil
+}
+
+// addStageResources registers the stage definition and stage file listing
+// resources. Stages themselves are listed through addSchemaListing.
+func addStageResources(s *server.MCPServer, mw resourceMiddleware, db *sqlx.DB) {
+	pat := regexp.MustCompile(`^snowflake://([^/]+)/([^/]+)/stage/([^/]+)(/files)?$`)
+	handler := func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		m, err := matchURI(pat, request.Params.URI)
+		if err != nil {
+			return nil, err
+		}
+		if m == nil {
+			return nil, fmt.Errorf("Invalid URI")
+		}
+		dbName, schemaName, stageName := m[1], m[2], m[3]
+
+		if m[4] == "" {
+			info, err := showStage(ctx, db, dbName, schemaName, stageName)
+			if err != nil {
+				return nil, err
+			}
+			return jsonResourceContents(request.Params.URI, map[string]any{
+				"stage": info,
+				"files": resourceURI(dbName, schemaName, "stage", stageName, "files"),
+			})
+		}
+
+		files, more, err := listStageFiles(ctx, db, dbName, schemaName, stageName)
+		if err != nil {
+			return nil, err
+		}
+		result := map[string]any{
+			"files": files,
+		}
+		if more {
+			result["notice"] = fmt.Sprintf("Only first %d files are shown", maxResultRows)
+		}
+		return jsonResourceContents(request.Params.URI, result)
+	}
+
+	addResourceTemplate(s, mcp.NewResourceTemplate(
+		"snowflake://{database-name}/{schema-name}/stage/{stage-name}",
+		"Stage definition",
+		mcp.WithTemplateDescription("Definition of a stage including whether it is internal or external and its URL"),
+		mcp.WithTemplateMIMEType("application/json"),
+	), mw.wrap(handler))
+
+	addResourceTemplate(s, mcp.NewResourceTemplate(
+		"snowflake://{database-name}/{schema-name}/stage/{stage-name}/files",
+		"Stage file list",
+		mcp.WithTemplateDescription(fmt.Sprintf("List of files in a stage with their sizes and last modified times, at most %d", maxResultRows)),
+		mcp.WithTemplateMIMEType("application/json"),
+	), mw.wrap(handler))
+}
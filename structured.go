@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// structuredResultSchema is the JSON schema of the structured results added
+// with -structured-output. MCP 2025-06-18 returns such results as
+// structuredContent described by the tool's outputSchema, which the protocol
+// version spoken by this server lacks, so the result is added as the last text
+// content instead and the schema is given in the tool description.
+const structuredResultSchema = `{"type":"object","properties":{` +
+	`"results":{"type":"array","items":{"type":"object","properties":{` +
+	`"columns":{"type":"array","items":{"type":"object","properties":{"name":{"type":"string"},"type":{"type":"string"}},"required":["name","type"]}},` +
+	`"rows":{"type":"array","items":{"type":"array"}},` +
+	`"row_count":{"type":"integer"},` +
+	`"notice":{"type":"string"}},` +
+	`"required":["columns","rows","row_count"]}},` +
+	`"query_id":{"type":"string"},` +
+	`"warnings":{"type":"array","items":{"type":"string"}}},` +
+	`"required":["results"]}`
+
+// structuredOutputDescription is appended to the description of tools
+// returning structured results.
+const structuredOutputDescription = " Unless the arrow format is used, the last content is the result as a JSON object matching this JSON schema: " + structuredResultSchema
+
+// structuredResult returns a query result from queryRunner.runQuery or
+// runMultiQuery in the shape of structuredResultSchema. Single results are
+// returned as the only entry of results.
+func structuredResult(result map[string]any) map[string]any {
+	results, ok := result["results"].([]map[string]any)
+	if !ok {
+		results = []map[string]any{result}
+	}
+	entries := make([]map[string]any, len(results))
+	for i, r := range results {
+		columns, _ := r["column_info"].([]map[string]any)
+		if columns == nil {
+			columns = []map[string]any{}
+		}
+		rows, _ := r["rows"].([][]any)
+		if rows == nil {
+			rows = [][]any{}
+		}
+		entry := map[string]any{
+			"columns":   columns,
+			"rows":      rows,
+			"row_count": len(rows),
+		}
+		if notice, ok := r["notice"].(string); ok {
+			entry["notice"] = notice
+		}
+		entries[i] = entry
+	}
+	ret := map[string]any{"results": entries}
+	if queryID, ok := result["query_id"].(string); ok {
+		ret["query_id"] = queryID
+	}
+	if warnings, ok := result["warnings"].([]string); ok {
+		ret["warnings"] = warnings
+	}
+	return ret
+}
+
+// addStructuredResult appends result to res as a compact JSON object in the
+// shape of structuredResultSchema.
+func addStructuredResult(res *mcp.CallToolResult, result map[string]any) error {
+	b, err := json.Marshal(structuredResult(result))
+	if err != nil {
+		return fmt.Errorf("Failed to marshal result: %v", err)
+	}
+	res.Content = append(res.Content, mcp.NewTextContent(string(b)))
+	return nil
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// toolRegistry registers tools on an MCP server, skipping disabled ones.
+type toolRegistry struct {
+	s        *server.MCPServer
+	disabled map[string]bool
+	// known is the set of all tools seen, whether disabled or not.
+	known     map[string]bool
+	warehouse *warehouseGuard
+	limiter   *queryLimiter
+	progress  *progressNotifier
+}
+
+func newToolRegistry(s *server.MCPServer, disabled []string, warehouse *warehouseGuard, limiter *queryLimiter, progress *progressNotifier) *toolRegistry {
+	r := &toolRegistry{
+		s:         s,
+		disabled:  map[string]bool{},
+		known:     map[string]bool{},
+		warehouse: warehouse,
+		limiter:   limiter,
+		progress:  progress,
+	}
+	for _, name := range disabled {
+		r.disabled[name] = true
+	}
+	return r
+}
+
+// add registers a tool unless it's disabled. Invocations are logged,
+// authentication and warehouse errors explained and invalid arguments
+// reported as tool errors. Calls failing for lack of a running warehouse are
+// retried once if it could be resumed. Calls wait for the limiter and are
+// reported as busy if none frees up in time. Clients asking for progress are
+// sent notifications while the call runs. Only the names of the arguments are
+// logged as their values may be sensitive.
+func (r *toolRegistry) add(tool mcp.Tool, handler server.ToolHandlerFunc) {
+	r.known[tool.Name] = true
+	if r.disabled[tool.Name] {
+		return
+	}
+	r.s.AddTool(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		args := make([]string, 0, len(request.Params.Arguments))
+		for k := range request.Params.Arguments {
+			args = append(args, k)
+		}
+		sort.Strings(args)
+		start := time.Now()
+		release, err := r.limiter.acquire(ctx)
+		if errors.Is(err, errBusy) {
+			slog.Warn("Tool call rejected", "tool", tool.Name, "args", args, "error", err)
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+		defer r.progress.start(tool.Name, request)()
+		result, err := handler(ctx, request)
+		if r.warehouse.resume(ctx, err) {
+			result, err = handler(ctx, request)
+		}
+		err = r.warehouse.explain(explainAuthError(err))
+		if err != nil {
+			slog.Error("Tool call failed", "tool", tool.Name, "args", args, "elapsed", time.Since(start), "error", err)
+		} else {
+			slog.Info("Tool called", "tool", tool.Name, "args", args, "elapsed", time.Since(start))
+		}
+		var argErr *argError
+		if errors.As(err, &argErr) {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		return result, err
+	})
+}
+
+// checkDisabled returns an error if any disabled tool doesn't exist, to catch
+// typos that would leave a tool exposed.
+func (r *toolRegistry) checkDisabled() error {
+	unknown := []string{}
+	for name := range r.disabled {
+		if !r.known[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("Unknown disabled tools: %s", strings.Join(unknown, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// callTool calls the tool name, as registered by register, with args through
+// the MCP server and returns its result. Protocol errors fail the test.
+func callTool(t *testing.T, register func(tools *toolRegistry), name string, args map[string]any) *mcp.CallToolResult {
+	t.Helper()
+	s := server.NewMCPServer("test", "test")
+	register(newToolRegistry(s, nil, nil, nil, nil))
+	msg, err := json.Marshal(map[string]any{
+		"jsonrpc": "2.0",
+		"id":      1,
+		"method":  "tools/call",
+		"params":  map[string]any{"name": name, "arguments": args},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	reply := s.HandleMessage(context.Background(), msg)
+	res, ok := reply.(mcp.JSONRPCResponse)
+	if !ok {
+		t.Fatalf("Calling %s failed: %+v", name, reply)
+	}
+	return res.Result.(*mcp.CallToolResult)
+}
+
+func TestDataToolsInvalidArguments(t *testing.T) {
+	db, f := newFakeDB(t, func(string) fakeResult { return fakeResult{} })
+	runner := &queryRunner{db: db}
+	allowed, err := newDatabaseAllowlist([]string{"DB"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	register := func(tools *toolRegistry) { registerDataTools(tools, db, runner, allowed) }
+	tests := []struct {
+		tool string
+		args map[string]any
+		want string
+	}{
+		{"data_quality", map[string]any{"table": "OTHER.S.T"}, "Access to database OTHER is not allowed"},
+		{"data_quality", map[string]any{"table": "T"}, "must be fully qualified"},
+		{"data_quality", map[string]any{"table": "DB.S.T", "key_columns": []any{`"a`}}, "Unterminated quoted identifier"},
+		{"data_quality", map[string]any{"table": "DB.S.T", "ranges": map[string]any{"A": map[string]any{"min": []any{}}}}, "Invalid minimum of A"},
+		{"data_quality", map[string]any{"table": "DB.S.T", "checks": []any{"typos"}}, `Unknown check "typos"`},
+		{"count_rows", map[string]any{"database": "OTHER", "schema": "S", "table": "T"}, "not allowed"},
+		{"count_rows", map[string]any{"database": "DB", "schema": "a.b", "table": "T"}, "Invalid identifier"},
+		{"count_rows", map[string]any{"database": "DB", "schema": "S", "table": "T", "where": "EXISTS (SELECT 1 FROM OTHER.S.U)"}, "not allowed"},
+		{"profile_column", map[string]any{"database": "DB", "schema": "S", "table": "T", "column": `"c`}, "Unterminated quoted identifier"},
+		{"clustering_info", map[string]any{"database": "DB", "schema": "S", "table": "T", "columns": []any{"a.b"}}, "Invalid identifier"},
+		{"preview_join", map[string]any{"left_table": "DB.S.T", "right_table": "OTHER.S.U"}, "not allowed"},
+		{"preview_join", map[string]any{"left_table": "DB.S.T", "right_table": "DB.S.U", "limit": 1.5}, "must be an integer"},
+		{"preview_join", map[string]any{"left_table": "DB.S.T", "right_table": "DB.S.U", "limit": 1e300}, "out of range"},
+		{"preview_join", map[string]any{"left_table": "DB.S.T", "right_table": "DB.S.U", "limit": 0}, "Limit must be between 1 and 100"},
+		{"preview_join", map[string]any{"left_table": "DB.S.T", "right_table": "DB.S.U", "limit": 101}, "Limit must be between 1 and 100"},
+	}
+	for _, tt := range tests {
+		res := callTool(t, register, tt.tool, tt.args)
+		if text := strings.Join(toolText(t, res), ""); !res.IsError || !strings.Contains(text, tt.want) {
+			t.Errorf("%s(%v) = %q, error %t, want an error containing %q", tt.tool, tt.args, text, res.IsError, tt.want)
+		}
+	}
+	if ran := f.ran(); len(ran) != 0 {
+		t.Errorf("Ran %q for invalid arguments", ran)
+	}
+}
+
+func TestQueryToolMultiReadOnly(t *testing.T) {
+	db, f := newFakeDB(t, func(string) fakeResult { return fakeResult{} })
+	register := func(tools *toolRegistry) {
+		registerQueryTools(tools, db, &queryRunner{db: db}, nil, queryToolConfig{defaultFormat: formatJSON, readOnly: true})
+	}
+	for query, want := range map[string]string{
+		"SELECT 1; DELETE FROM t":   "Only read-only queries",
+		"SELECT 1; /* x */ DROP t;": "Only read-only queries",
+		" ; ; ":                     "Query has no statements",
+		"-- only a comment":         "Only read-only queries",
+	} {
+		res := callTool(t, register, "query", map[string]any{"query": query, "multi": true})
+		if text := strings.Join(toolText(t, res), ""); !res.IsError || !strings.Contains(text, want) {
+			t.Errorf("query(%q) = %q, error %t, want an error containing %q", query, text, res.IsError, want)
+		}
+	}
+	if ran := f.ran(); len(ran) != 0 {
+		t.Errorf("Ran %q", ran)
+	}
+}
+
+func TestAbortSessionRefusesOwnSessions(t *testing.T) {
+	f := &fakeDB{respond: func(query string) fakeResult {
+		switch {
+		case query == "SELECT CURRENT_SESSION()":
+			return fakeResult{columns: []string{"CURRENT_SESSION()"}, rows: [][]driver.Value{{"42"}}}
+		case strings.HasPrefix(query, "SELECT SYSTEM$ABORT_SESSION"):
+			return fakeResult{columns: []string{"STATUS"}, rows: [][]driver.Value{{"Session aborted"}}}
+		}
+		return fakeResult{columns: []string{"SESSION_ID"}}
+	}}
+	own := newSessionTracker(f)
+	db := sqlx.NewDb(sql.OpenDB(own), "snowflake").Unsafe()
+	defer db.Close()
+	register := func(tools *toolRegistry) { registerSessionTools(tools, db, &queryRunner{db: db}, own, nil) }
+
+	res := callTool(t, register, "show_sessions", nil)
+	var sessions map[string]any
+	if err := json.Unmarshal([]byte(strings.Join(toolText(t, res), "")), &sessions); err != nil {
+		t.Fatal(err)
+	}
+	if got := sessions["server_session_ids"]; !reflect.DeepEqual(got, []any{"42"}) {
+		t.Errorf("Server session IDs are %v, want [42]", got)
+	}
+
+	res = callTool(t, register, "abort_session", map[string]any{"session_id": "42"})
+	if text := strings.Join(toolText(t, res), ""); !res.IsError || !strings.Contains(text, "server's own sessions") {
+		t.Errorf("Aborting an own session returned %q", text)
+	}
+	res = callTool(t, register, "abort_session", map[string]any{"session_id": "7"})
+	if text := strings.Join(toolText(t, res), ""); res.IsError || text != "Session aborted" {
+		t.Errorf("Aborting another session returned %q", text)
+	}
+	if ran := f.ran(); ran[len(ran)-1] != "SELECT SYSTEM$ABORT_SESSION(?)" {
+		t.Errorf("Ran %q", ran)
+	}
+}
+
+func TestGetResultsMaxPages(t *testing.T) {
+	db, f := newFakeDB(t, func(string) fakeResult {
+		return fakeResult{columns: []string{"N"}, rows: [][]driver.Value{{int64(1)}}}
+	})
+	cursors := newResultCursors(2)
+	register := func(tools *toolRegistry) {
+		registerQueryTools(tools, db, &queryRunner{db: db}, nil, queryToolConfig{defaultFormat: formatJSON, resultCursors: cursors})
+	}
+	const queryID = "01b2c3d4-0000-0000-0000-000000000001"
+	for _, offset := range []int{0, 1000, 0} {
+		if res := callTool(t, register, "get_results", map[string]any{"query_id": queryID, "offset": offset}); res.IsError {
+			t.Fatalf("get_results at %d failed: %q", offset, toolText(t, res))
+		}
+	}
+	ran := len(f.ran())
+	res := callTool(t, register, "get_results", map[string]any{"query_id": queryID, "offset": 2000})
+	if text := strings.Join(toolText(t, res), ""); !res.IsError || !strings.Contains(text, "Already fetched 2 pages") {
+		t.Errorf("Third page returned %q", text)
+	}
+	if len(f.ran()) != ran {
+		t.Errorf("Ran %q beyond the maximum pages", f.ran()[ran:])
+	}
+	if n := cursors.count(); n != 1 {
+		t.Errorf("%d results are open, want 1", n)
+	}
+
+	for _, want := range []string{"Closed results", "were not open"} {
+		res = callTool(t, register, "close_results", map[string]any{"query_id": queryID})
+		if text := strings.Join(toolText(t, res), ""); res.IsError || !strings.Contains(text, want) {
+			t.Errorf("close_results returned %q, want %q", text, want)
+		}
+	}
+	if n := cursors.count(); n != 0 {
+		t.Errorf("%d results are open after closing", n)
+	}
+	if res := callTool(t, register, "get_results", map[string]any{"query_id": queryID, "offset": 2000}); res.IsError {
+		t.Errorf("get_results after closing failed: %q", toolText(t, res))
+	}
+}
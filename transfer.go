@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// stageRefPat matches stage references such as @db.schema.stage/path,
+// @%table and @~, allowing quoted names.
+var stageRefPat = regexp.MustCompile(`^@(?:"[^"]*"|[^\s;'"])+$`)
+
+// checkStageRef returns an error if stage is not a valid stage reference or
+// names a database that is not allowed.
+func checkStageRef(allowed databaseAllowlist, stage string) error {
+	if !stageRefPat.MatchString(stage) {
+		return newArgError("Invalid stage %q, must be like @db.schema.stage/path, @%%table or @~", stage)
+	}
+	name, _, _ := strings.Cut(strings.TrimPrefix(stage, "@"), "/")
+	if name == "~" {
+		return nil
+	}
+	// Table stages are named after their table with a % in front.
+	parts, err := splitQualifiedName(strings.Replace(name, "%", "", 1))
+	if err != nil {
+		return newArgError("Invalid stage %q: %v", stage, err)
+	}
+	if len(parts) > 3 {
+		return newArgError("Invalid stage %q, must be like @db.schema.stage/path, @%%table or @~", stage)
+	}
+	if len(parts) == 3 {
+		return allowed.check(parts[0])
+	}
+	return nil
+}
+
+// transferPath returns the absolute path of name within dir. name must be
+// relative and must not lead out of dir, also through symbolic links.
+func transferPath(dir, name string) (string, error) {
+	if !filepath.IsLocal(name) {
+		return "", newArgError("Path %q must be relative to the transfer directory and stay within it", name)
+	}
+	if strings.ContainsAny(name, "'\n") {
+		return "", newArgError("Path %q must not contain quotes or newlines", name)
+	}
+	root, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return "", fmt.Errorf("Failed to resolve transfer directory: %w", err)
+	}
+	root, err = filepath.Abs(root)
+	if err != nil {
+		return "", fmt.Errorf("Failed to resolve transfer directory: %w", err)
+	}
+	path, err := filepath.EvalSymlinks(filepath.Join(root, name))
+	if err != nil {
+		return "", newArgError("Failed to resolve %s: %v", name, err)
+	}
+	if rel, err := filepath.Rel(root, path); err != nil || !filepath.IsLocal(rel) {
+		return "", newArgError("Path %q leads out of the transfer directory", name)
+	}
+	return path, nil
+}
+
+// putFile uploads the file at path to stage and returns the outcome including
+// the size and compression of the file before and after uploading.
+func putFile(ctx context.Context, runner *queryRunner, path, stage string, autoCompress, overwrite bool) (map[string]any, error) {
+	stmt := fmt.Sprintf("PUT 'file://%s' %s AUTO_COMPRESS = %t OVERWRITE = %t", filepath.ToSlash(path), stage, autoCompress, overwrite)
+	result, err := runner.runQuery(ctx, stmt)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to upload %s: %w", path, err)
+	}
+	return result, nil
+}
+
+// getFile downloads the files at stage, which may be a single file or a path
+// prefix, to the directory dir and returns the outcome of each file including
+// its size.
+func getFile(ctx context.Context, runner *queryRunner, stage, dir string) (map[string]any, error) {
+	stmt := fmt.Sprintf("GET %s 'file://%s/'", stage, filepath.ToSlash(dir))
+	result, err := runner.runQuery(ctx, stmt)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to download %s: %w", stage, err)
+	}
+	return result, nil
+}
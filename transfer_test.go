@@ -0,0 +1,92 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckStageRef(t *testing.T) {
+	allowed, err := newDatabaseAllowlist([]string{"SALES"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		stage string
+		ok    bool
+	}{
+		{"@~", true},
+		{"@~/dir/file.csv", true},
+		{"@%orders", true},
+		{"@%orders/file.csv", true},
+		{"@stg", true},
+		{"@public.stg/path/", true},
+		{"@sales.public.stg", true},
+		{"@SALES.PUBLIC.STG/file.csv.gz", true},
+		{`@"SALES"."my schema"."my stage"/f`, true},
+		{"@other.public.stg", false},
+		{`@"sales".public.stg`, false},
+		{"@%other.public.orders", false},
+		{"stg", false},
+		{"@", false},
+		{"@stg file", false},
+		{"@stg;DROP TABLE t", false},
+		{"@stg'", false},
+		{"@a.b.c.d", false},
+	}
+	for _, tt := range tests {
+		if err := checkStageRef(allowed, tt.stage); (err == nil) != tt.ok {
+			t.Errorf("checkStageRef(%q) = %v, want ok %t", tt.stage, err, tt.ok)
+		}
+	}
+}
+
+func TestTransferPath(t *testing.T) {
+	dir := t.TempDir()
+	outside := t.TempDir()
+	for _, name := range []string{"a.csv", "sub/b.csv"} {
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(outside, filepath.Join(dir, "escape")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(dir, "sub"), filepath.Join(dir, "inside")); err != nil {
+		t.Fatal(err)
+	}
+	root, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"a.csv", filepath.Join(root, "a.csv")},
+		{"sub/b.csv", filepath.Join(root, "sub", "b.csv")},
+		{"sub/../a.csv", filepath.Join(root, "a.csv")},
+		{"sub", filepath.Join(root, "sub")},
+		{"inside/b.csv", filepath.Join(root, "sub", "b.csv")},
+	}
+	for _, tt := range tests {
+		got, err := transferPath(dir, tt.name)
+		if err != nil || got != tt.want {
+			t.Errorf("transferPath(%q) = %q, %v, want %q", tt.name, got, err, tt.want)
+		}
+	}
+
+	for _, name := range []string{"../a.csv", "/etc/passwd", "", "escape", "escape/x", "missing.csv", "it's.csv", "a\nb"} {
+		_, err := transferPath(dir, name)
+		var argErr *argError
+		if !errors.As(err, &argErr) {
+			t.Errorf("transferPath(%q) = %v, want an argument error", name, err)
+		}
+	}
+}
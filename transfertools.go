@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// registerTransferTools registers the tools transferring files between
+// stages and dir.
+func registerTransferTools(tools *toolRegistry, runner *queryRunner, allowed databaseAllowlist, dir string) {
+	// Add file transfer tools. Local paths are confined to the transfer
+	// directory. Uploading is disabled in read-only mode.
+	tools.add(mcp.NewTool(
+		"put_file",
+		mcp.WithDescription("Upload a local file to a stage with PUT, e.g. to load it into a table with COPY INTO afterwards. Returns the size and compression of the file before and after uploading."),
+		mcp.WithString("path",
+			mcp.Required(),
+			mcp.Description("Path of the file relative to the transfer directory of the server."),
+		),
+		mcp.WithString("stage",
+			mcp.Required(),
+			mcp.Description("Stage and optional path to upload to, e.g. @db.schema.stage/dir/, @%table for a table stage or @~ for the user stage."),
+		),
+		mcp.WithBoolean("auto_compress",
+			mcp.Description("Compress the file with gzip unless it is already compressed."),
+			mcp.DefaultBool(true),
+		),
+		mcp.WithBoolean("overwrite",
+			mcp.Description("Overwrite a file of the same name in the stage."),
+			mcp.DefaultBool(false),
+		),
+	), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		name, err := stringArg(request.Params.Arguments, "path", true)
+		if err != nil {
+			return nil, err
+		}
+		stage, err := stringArg(request.Params.Arguments, "stage", true)
+		if err != nil {
+			return nil, err
+		}
+		autoCompress, err := boolArg(request.Params.Arguments, "auto_compress", true)
+		if err != nil {
+			return nil, err
+		}
+		overwrite, err := boolArg(request.Params.Arguments, "overwrite", false)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkStageRef(allowed, stage); err != nil {
+			return nil, err
+		}
+		path, err := transferPath(dir, name)
+		if err != nil {
+			return nil, err
+		}
+		result, err := putFile(ctx, runner, path, stage, autoCompress, overwrite)
+		if err != nil {
+			return nil, err
+		}
+		return jsonToolResult(result)
+	})
+
+	tools.add(mcp.NewTool(
+		"get_file",
+		mcp.WithDescription("Download files from a stage with GET, e.g. after unloading query results with COPY INTO @stage. Returns the size of each downloaded file."),
+		mcp.WithString("stage",
+			mcp.Required(),
+			mcp.Description("Stage and path of the file, or a path prefix to download several files, e.g. @db.schema.stage/dir/file.csv.gz."),
+		),
+		mcp.WithString("directory",
+			mcp.Description("Directory to download to, relative to the transfer directory of the server. Defaults to the transfer directory itself."),
+		),
+	), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		stage, err := stringArg(request.Params.Arguments, "stage", true)
+		if err != nil {
+			return nil, err
+		}
+		name, err := stringArg(request.Params.Arguments, "directory", false)
+		if err != nil {
+			return nil, err
+		}
+		if name == "" {
+			name = "."
+		}
+		if err := checkStageRef(allowed, stage); err != nil {
+			return nil, err
+		}
+		dir, err := transferPath(dir, name)
+		if err != nil {
+			return nil, err
+		}
+		result, err := getFile(ctx, runner, stage, dir)
+		if err != nil {
+			return nil, err
+		}
+		return jsonToolResult(result)
+	})
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// matchURI matches a resource URI against pat, tolerating a trailing slash,
+// and returns the URL decoded captured segments, or nil if it doesn't match.
+func matchURI(pat *regexp.Regexp, uri string) ([]string, error) {
+	if !strings.HasSuffix(uri, "://") {
+		uri = strings.TrimSuffix(uri, "/")
+	}
+	m := pat.FindStringSubmatch(uri)
+	if m == nil {
+		return nil, nil
+	}
+	for i := 1; i < len(m); i++ {
+		s, err := url.PathUnescape(m[i])
+		if err != nil {
+			return nil, fmt.Errorf("Invalid URI segment %q: %v", m[i], err)
+		}
+		m[i] = s
+	}
+	return m, nil
+}
+
+// resourceURI builds a snowflake:// resource URI from URL encoded path
+// segments.
+func resourceURI(segments ...string) string {
+	escaped := make([]string, len(segments))
+	for i, s := range segments {
+		escaped[i] = url.PathEscape(s)
+	}
+	return "snowflake://" + strings.Join(escaped, "/")
+}
+
+var unquotedIdentPat = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_$]*$`)
+
+// sqlIdent returns name for use in SQL. Names that are valid unquoted
+// identifiers are passed through so that they resolve case insensitively as
+// usual, unless quoteIdentifiers is set, while others such as names with
+// spaces are quoted.
+func sqlIdent(name string) string {
+	if !quoteIdentifiers && unquotedIdentPat.MatchString(name) {
+		return name
+	}
+	return quoteIdent(name)
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"database/sql/driver"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestReadResourceURIs(t *testing.T) {
+	respond := func(query string) fakeResult {
+		if strings.HasPrefix(query, "DESCRIBE TABLE") {
+			return fakeResult{
+				columns: []string{"name", "type", "kind", "null?"},
+				rows:    [][]driver.Value{{"ID", "NUMBER(38,0)", "COLUMN", "N"}},
+			}
+		}
+		return fakeResult{columns: []string{"name"}}
+	}
+	tests := []struct {
+		uri   string
+		query string
+	}{
+		{"snowflake://DB", "SHOW TERSE SCHEMAS IN DATABASE DB"},
+		{"snowflake://DB/", "SHOW TERSE SCHEMAS IN DATABASE DB"},
+		{"snowflake://DB/?limit=5", "SHOW TERSE SCHEMAS IN DATABASE DB LIMIT 6"},
+		{"snowflake://DB/PUBLIC/tables/", "SHOW TERSE TABLES IN SCHEMA DB.PUBLIC"},
+		{"snowflake://DB/PUBLIC/tables/?offset=1&limit=1", "SHOW TERSE TABLES IN SCHEMA DB.PUBLIC LIMIT 3"},
+		{"snowflake://DB/PUBLIC/table/ORDERS", "DESCRIBE TABLE DB.PUBLIC.ORDERS"},
+		{"snowflake://DB/PUBLIC/table/ORDERS/", "DESCRIBE TABLE DB.PUBLIC.ORDERS"},
+		{"snowflake://DB/PUBLIC/view/my%20view/", `DESCRIBE TABLE DB.PUBLIC."my view"`},
+		{"snowflake://my%20db/PUBLIC/table/a%2Fb%25c%22d", `DESCRIBE TABLE "my db".PUBLIC."a/b%c""d"`},
+		{"snowflake://DB/PUBLIC/table/T%C3%A9", `DESCRIBE TABLE DB.PUBLIC."Té"`},
+		{"snowflake://DB/PUBLIC/stage/STG/files/", "LIST @DB.PUBLIC.STG"},
+	}
+	for _, tt := range tests {
+		_, f := readResource(t, tt.uri, respond)
+		if ran := f.ran(); len(ran) == 0 || ran[0] != tt.query {
+			t.Errorf("%s ran %q, want %q first", tt.uri, ran, tt.query)
+		}
+	}
+}
+
+func TestMatchURIInvalidEscape(t *testing.T) {
+	pat := regexp.MustCompile(`^snowflake://([^/]+)$`)
+	if _, err := matchURI(pat, "snowflake://a%zzb"); err == nil {
+		t.Error("matchURI accepted an invalid escape")
+	}
+}
+
+func TestResourceURI(t *testing.T) {
+	tests := []struct {
+		segments []string
+		want     string
+	}{
+		{[]string{"DB", "PUBLIC", "table", "ORDERS"}, "snowflake://DB/PUBLIC/table/ORDERS"},
+		{[]string{"my db", "PUBLIC", "table", "a/b"}, "snowflake://my%20db/PUBLIC/table/a%2Fb"},
+		{[]string{"DB", "S", "table", "50%?"}, "snowflake://DB/S/table/50%25%3F"},
+	}
+	for _, tt := range tests {
+		if got := resourceURI(tt.segments...); got != tt.want {
+			t.Errorf("resourceURI(%q) = %q, want %q", tt.segments, got, tt.want)
+		}
+	}
+}
+
+func TestResourceURIRoundTrip(t *testing.T) {
+	pat := regexp.MustCompile(`^snowflake://([^/]+)/([^/]+)/table/([^/]+)$`)
+	for _, name := range []string{"plain", "with space", "slash/in/name", "per%cent", "quote\"d", "ünïcode", "q?a#b"} {
+		m, err := matchURI(pat, resourceURI("DB", "S", "table", name))
+		if err != nil || m == nil {
+			t.Errorf("URI for %q didn't match: %v", name, err)
+			continue
+		}
+		if m[3] != name {
+			t.Errorf("URI for %q matched %q", name, m[3])
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/snowflakedb/gosnowflake"
+)
+
+// validateQuery compiles query without executing it and reports whether it
+// is valid, along with the columns it would return or the Snowflake error.
+func validateQuery(ctx context.Context, db *sqlx.DB, query string, args ...any) map[string]any {
+	rows, err := db.QueryxContext(gosnowflake.WithDescribeOnly(ctx), query, args...)
+	if err != nil {
+		return map[string]any{
+			"valid": false,
+			"error": describeError(err),
+		}
+	}
+	defer rows.Close()
+
+	columnInfo := []map[string]any{}
+	if columnTypes, err := rows.ColumnTypes(); err == nil {
+		for _, columnType := range columnTypes {
+			columnInfo = append(columnInfo, map[string]any{
+				"name": columnType.Name(),
+				"type": columnType.DatabaseTypeName(),
+			})
+		}
+	}
+	return map[string]any{
+		"valid":       true,
+		"column_info": columnInfo,
+	}
+}
+
+// describeError returns the details of err, including the Snowflake error
+// code and SQL state when available.
+func describeError(err error) map[string]any {
+	detail := map[string]any{
+		"message": err.Error(),
+	}
+	var sfErr *gosnowflake.SnowflakeError
+	if errors.As(err, &sfErr) {
+		detail["message"] = sfErr.Message
+		detail["code"] = sfErr.Number
+		if sfErr.SQLState != "" {
+			detail["sql_state"] = sfErr.SQLState
+		}
+	}
+	return detail
+}
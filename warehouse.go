@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/snowflakedb/gosnowflake"
+)
+
+// errCodeNoActiveWarehouse is returned by Snowflake when a query needs a
+// warehouse but the session has none running, e.g. because it is suspended
+// and doesn't auto-resume.
+const errCodeNoActiveWarehouse = 606
+
+// isNoWarehouseError reports whether err is due to the session not having a
+// running warehouse.
+func isNoWarehouseError(err error) bool {
+	var sfErr *gosnowflake.SnowflakeError
+	return errors.As(err, &sfErr) && sfErr.Number == errCodeNoActiveWarehouse
+}
+
+// warehouseGuard deals with queries failing because the configured warehouse
+// is suspended.
+type warehouseGuard struct {
+	db *sqlx.DB
+	// name is the configured warehouse, if any.
+	name string
+	// autoResume makes the guard resume the warehouse so that the query can
+	// be retried.
+	autoResume bool
+}
+
+// resume resumes the warehouse if err is due to it not running and
+// auto-resume is enabled, reporting whether the failed query should be
+// retried.
+func (g *warehouseGuard) resume(ctx context.Context, err error) bool {
+	if g == nil || !g.autoResume || g.name == "" || !isNoWarehouseError(err) {
+		return false
+	}
+	slog.Info("Resuming warehouse", "warehouse", g.name)
+	if _, err := g.db.ExecContext(ctx, "ALTER WAREHOUSE "+sqlIdent(g.name)+" RESUME IF SUSPENDED"); err != nil {
+		slog.Warn("Failed to resume warehouse", "warehouse", g.name, "error", err)
+		return false
+	}
+	return true
+}
+
+// explain replaces errors due to no warehouse running with a message telling
+// what to do. Other errors are returned as is.
+func (g *warehouseGuard) explain(err error) error {
+	if err == nil || !isNoWarehouseError(err) {
+		return err
+	}
+	if g == nil || g.name == "" {
+		return fmt.Errorf("No warehouse is configured. The operator needs to set -warehouse, or the query needs to select one with USE WAREHOUSE: %w", err)
+	}
+	return fmt.Errorf("Warehouse %s is not running, likely because it is suspended and doesn't resume automatically or the role can't use it. Resume it with ALTER WAREHOUSE %s RESUME, or have the operator enable -auto-resume: %w", g.name, g.name, err)
+}
+
+// withWarehouse returns a copy of r running queries on a dedicated
+// connection using warehouse, so that switching warehouses doesn't affect
+// other connections of the pool. The returned function must be called once
+// done to switch the connection back to its previous warehouse and return it
+// to the pool. If that isn't possible, the connection is discarded instead.
+// If r already runs on a dedicated connection, that one is switched since it
+// is discarded afterwards anyway.
+func (r *queryRunner) withWarehouse(ctx context.Context, warehouse string) (*queryRunner, func(), error) {
+	if r.conn != nil {
+		if _, err := r.conn.ExecContext(ctx, "USE WAREHOUSE "+quoteIdent(warehouse)); err != nil {
+			return nil, nil, fmt.Errorf("Failed to use warehouse %s: %w", warehouse, err)
+		}
+		return r, func() {}, nil
+	}
+	conn, err := r.db.Connx(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Failed to get a connection: %w", err)
+	}
+	var previous *string
+	if err := conn.GetContext(ctx, &previous, "SELECT CURRENT_WAREHOUSE()"); err != nil {
+		discardConn(conn)
+		return nil, nil, fmt.Errorf("Failed to get current warehouse: %w", err)
+	}
+	if _, err := conn.ExecContext(ctx, "USE WAREHOUSE "+quoteIdent(warehouse)); err != nil {
+		discardConn(conn)
+		return nil, nil, fmt.Errorf("Failed to use warehouse %s: %w", warehouse, err)
+	}
+	c := *r
+	c.conn = conn
+	release := func() {
+		// The session can't go back to having no warehouse.
+		if previous == nil {
+			discardConn(conn)
+			return
+		}
+		if _, err := conn.ExecContext(context.Background(), "USE WAREHOUSE "+quoteIdent(*previous)); err != nil {
+			slog.Warn("Failed to restore warehouse, discarding connection", "warehouse", *previous, "error", err)
+			discardConn(conn)
+			return
+		}
+		conn.Close()
+	}
+	return &c, release, nil
+}
+
+// discardConn closes conn and removes it from the pool.
+func discardConn(conn *sqlx.Conn) {
+	conn.Raw(func(any) error { return driver.ErrBadConn })
+	conn.Close()
+}